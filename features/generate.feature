@@ -119,3 +119,1212 @@ Feature: Generate project directories
     cmd
     internal
     """
+
+  Scenario: skip directories and files
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: README.md
+          - name: LICENSE
+            skip: true
+        directories:
+          - name: cmd
+          - name: internal
+            skip: true
+            files:
+              - name: domain.go
+    """
+    And a ".values.yml" file with the following contents
+    """
+    README.md.tmpl: {}
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    ls funditest
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    README.md
+    cmd
+    """
+
+  Scenario: generate directories and files conditionally
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+      variables:
+        withDatabase: false
+        withDocs: true
+    directories:
+      - name: funditest
+        files:
+          - name: README.md
+            when: '{{ .withDocs }}'
+          - name: schema.sql
+            when: '{{ .withDatabase }}'
+        directories:
+          - name: cmd
+          - name: postgres
+            when: '{{ .withDatabase }}'
+    """
+    And a ".values.yml" file with the following contents
+    """
+    README.md.tmpl: {}
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    ls funditest
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    README.md
+    cmd
+    """
+
+  Scenario: generate directories and files with templated names
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+      variables:
+        project: funditest
+        service: billing
+    directories:
+      - name: "{{ .project }}"
+        directories:
+          - name: internal
+            files:
+              - name: "{{ .service }}_handler.go"
+    """
+    And a ".values.yml" file with the following contents
+    """
+    handler.go.tmpl: {}
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    ls funditest/internal
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    billing_handler.go
+    """
+
+  Scenario: generate a directory for each element of a list
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+      variables:
+        domains:
+          - billing
+          - payments
+    directories:
+      - name: funditest
+        directories:
+          - name: "{{ .item }}"
+            foreach: domains
+            files:
+              - name: service.go
+                template: service.go.tmpl
+    """
+    And a "service.go.tmpl" file with the following contents
+    """
+    package {{.package}}
+    """
+    And a ".values.yml" file with the following contents
+    """
+    service.go.tmpl:
+      package: "{{ .item }}"
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    ls funditest
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    billing
+    payments
+    """
+    When I execute the cli command
+    """
+    cat funditest/payments/service.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    package payments
+    """
+
+  Scenario: preview the project with a dry run
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: README.md
+          - name: LICENSE
+            skip: true
+        directories:
+          - name: cmd
+            files:
+              - name: main.go
+    """
+    And a ".values.yml" file with the following contents
+    """
+    README.md.tmpl: {}
+    """
+    When I execute the cli command
+    """
+    fundi generate --dry-run -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    create    funditest/
+    create    funditest/README.md
+    create    funditest/cmd/
+    create    funditest/cmd/main.go
+    skip      funditest/LICENSE
+    """
+    When I execute the cli command
+    """
+    ls funditest
+    """
+    Then I must get an exit code 1
+
+  Scenario: keep files that were edited since they were generated
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: README.md
+            template: README.md.tmpl
+    """
+    And a "README.md.tmpl" file with the following contents
+    """
+    # generated
+    """
+    And a "README.md" file with the following contents
+    """
+    # edited
+    """
+    And a ".values.yml" file with the following contents
+    """
+    README.md.tmpl: {}
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cp testdata/README.md funditest/README.md
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/README.md
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    # edited
+    """
+    When I execute the cli command
+    """
+    fundi generate --on-conflict=fail -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 1
+    When I execute the cli command
+    """
+    fundi generate --on-conflict=prompt -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 1
+    When I execute the cli command
+    """
+    fundi generate --dry-run --on-conflict=fail -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi generate --dry-run --on-conflict=prompt -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi generate --dry-run --on-conflict=backup -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    conflict (backup) funditest/README.md
+    """
+    When I execute the cli command
+    """
+    cat funditest/README.md.bak
+    """
+    Then I must get an exit code 1
+    When I execute the cli command
+    """
+    fundi generate --on-conflict=overwrite -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/README.md
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    # generated
+    """
+
+  Scenario: show how the files on disk differ from the generated project
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: README.md
+            template: README.md.tmpl
+        directories:
+          - name: cmd
+    """
+    And a "README.md.tmpl" file with the following contents
+    """
+    # generated
+    """
+    And a "README.md" file with the following contents
+    """
+    # edited
+    """
+    And a ".values.yml" file with the following contents
+    """
+    README.md.tmpl: {}
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi diff -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cp testdata/README.md funditest/README.md
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi diff -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 1
+    And I must get a command output
+    """
+    --- a/funditest/README.md
+    +++ b/funditest/README.md
+    @@ -1 +1 @@
+    -# edited
+    +# generated
+    """
+
+  Scenario: merge changes to the templates into files that were edited since they were generated
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: README.md
+            template: README.md.tmpl
+    """
+    And a "README.md.tmpl" file with the following contents
+    """
+    # funditest
+
+    Generated by fundi.
+    """
+    And a "README.md" file with the following contents
+    """
+    # funditest
+
+    Edited by hand.
+    """
+    And a ".values.yml" file with the following contents
+    """
+    README.md.tmpl: {}
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cp testdata/README.md funditest/README.md
+    """
+    Then I must get an exit code 0
+    And a "README.md.tmpl" file with the following contents
+    """
+    # Fundi Test
+
+    Generated by fundi.
+    """
+    When I execute the cli command
+    """
+    fundi update -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    merge     funditest/README.md
+    """
+    When I execute the cli command
+    """
+    cat funditest/README.md
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    # Fundi Test
+
+    Edited by hand.
+    """
+
+  Scenario: clean up a generated project but keep the files that were edited
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: README.md
+            template: README.md.tmpl
+        directories:
+          - name: cmd
+            files:
+              - name: main.go
+    """
+    And a "README.md.tmpl" file with the following contents
+    """
+    # generated
+    """
+    And a "README.md" file with the following contents
+    """
+    # edited
+    """
+    And a ".values.yml" file with the following contents
+    """
+    README.md.tmpl: {}
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cp testdata/README.md funditest/README.md
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi clean -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    keep      funditest/README.md (modified since it was generated)
+    remove    funditest/cmd/main.go
+    remove    funditest/cmd/
+    """
+    When I execute the cli command
+    """
+    fundi clean --force .
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    remove    funditest/README.md
+    remove    funditest/
+    """
+    When I execute the cli command
+    """
+    ls .fundi.lock
+    """
+    Then I must get an exit code 1
+
+  Scenario: leave the output directory as it was when generating fails
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: README.md
+        directories:
+          - name: cmd
+            files:
+              - name: main.go
+                template: broken.go.tmpl
+    """
+    And a "broken.go.tmpl" file with the following contents
+    """
+    package {{ .package
+    """
+    And a ".values.yml" file with the following contents
+    """
+    broken.go.tmpl:
+      package: main
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 1
+    When I execute the cli command
+    """
+    ls funditest
+    """
+    Then I must get an exit code 1
+
+  Scenario: fail on missing template values in strict mode
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+      strict: true
+    directories:
+      - name: funditest
+        files:
+          - name: main.go
+            template: main.go.tmpl
+    """
+    And a "main.go.tmpl" file with the following contents
+    """
+    package {{.package}}
+    """
+    And a ".values.yml" file with the following contents
+    """
+    main.go.tmpl:
+      name: main
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 1
+    When I execute the cli command
+    """
+    ls funditest
+    """
+    Then I must get an exit code 1
+
+  Scenario: generate files with template functions
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+      variables:
+        service: BillingService
+    directories:
+      - name: funditest
+        files:
+          - name: service.go
+            template: service.go.tmpl
+    """
+    And a "service.go.tmpl" file with the following contents
+    """
+    package {{ .package }}
+
+    type {{ pascalCase .entity }} struct{}
+    """
+    And a ".values.yml" file with the following contents
+    """
+    service.go.tmpl:
+      package: {{ snakeCase .service }}
+      entity: {{ singularize "invoices" }}
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/service.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    package billing_service
+
+    type Invoice struct{}
+    """
+
+  Scenario: generate files that pull in shared partials
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: main.go
+            template: main.go.tmpl
+    """
+    And a "_partials/license.tmpl" file with the following contents
+    """
+    // Copyright {{ .owner }}. All rights reserved.
+    """
+    And a "main.go.tmpl" file with the following contents
+    """
+    {{ template "license" . }}
+    package {{ .package }}
+    """
+    And a ".values.yml" file with the following contents
+    """
+    main.go.tmpl:
+      owner: Fundi
+      package: main
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/main.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    // Copyright Fundi. All rights reserved.
+    package main
+    """
+
+  Scenario: generate files from the global variables and the fundi context
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+      variables:
+        module: github.com/kasulani/funditest
+    directories:
+      - name: funditest
+        directories:
+          - name: billing
+            files:
+              - name: service.go
+                template: service.go.tmpl
+    """
+    And a "service.go.tmpl" file with the following contents
+    """
+    // Package {{ .Fundi.Dir }} is part of {{ .module }}.
+    package {{ .Fundi.Dir }}
+    """
+    And a ".values.yml" file with the following contents
+    """
+    service.go.tmpl: {}
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/billing/service.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    // Package billing is part of github.com/kasulani/funditest.
+    package billing
+    """
+
+  Scenario: generate several files from one template with values of their own
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: users.go
+            template: handler.go.tmpl
+            values:
+              resource: User
+          - name: orders.go
+            template: handler.go.tmpl
+            values:
+              resource: Order
+    """
+    And a "handler.go.tmpl" file with the following contents
+    """
+    package {{ .package }}
+
+    type {{ .resource }}Handler struct{}
+    """
+    And a ".values.yml" file with the following contents
+    """
+    handler.go.tmpl:
+      package: handlers
+      resource: Resource
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/orders.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    package handlers
+
+    type OrderHandler struct{}
+    """
+
+  Scenario: generate files from inline content in the configuration file
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: doc.go
+            values:
+              package: funditest
+            content: |
+              // Package {{ .package }} is generated.
+              package {{ .package }}
+    """
+    And a ".values.yml" file with the following contents
+    """
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/doc.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    // Package funditest is generated.
+    package funditest
+    """
+
+  Scenario: copy static assets verbatim
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+      copy:
+        - "*.sh"
+    directories:
+      - name: funditest
+        files:
+          - name: ci.yml
+            template: ci.yml
+            copy: true
+          - name: build.sh
+            template: build.sh
+    """
+    And a "ci.yml" file with the following contents
+    """
+    run: echo ${{ github.sha }}
+    """
+    And a "build.sh" file with the following contents
+    """
+    #!/bin/sh
+    go build {{ .flags }}
+    """
+    And a ".values.yml" file with the following contents
+    """
+    """
+    When I execute the cli command
+    """
+    chmod 755 testdata/build.sh
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/ci.yml
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    run: echo ${{ github.sha }}
+    """
+    When I execute the cli command
+    """
+    cat funditest/build.sh
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    #!/bin/sh
+    go build {{ .flags }}
+    """
+    When I execute the cli command
+    """
+    stat -c %a funditest/build.sh
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    755
+    """
+
+  Scenario: generate executable scripts, private directories and symlinks
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        directories:
+          - name: scripts
+            files:
+              - name: build.sh
+                mode: "0755"
+                content: |
+                  #!/bin/sh
+                  go build ./...
+          - name: secrets
+            mode: "0700"
+          - name: bin
+            files:
+              - name: build
+                symlink: ../scripts/build.sh
+    """
+    And a ".values.yml" file with the following contents
+    """
+    """
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    stat -c %a funditest/scripts/build.sh funditest/secrets
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    755
+    700
+    """
+    When I execute the cli command
+    """
+    readlink funditest/bin/build
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    ../scripts/build.sh
+    """
+    When I execute the cli command
+    """
+    fundi clean -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    ls funditest
+    """
+    Then I must get an exit code 1
+
+  Scenario: generate files from templates in a git repository
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "git+file:///tmp/fundi-blueprints.git//go-service?ref=v1.0.0"
+      values: "./testdata/.values.yml"
+      variables:
+        name: funditest
+    directories:
+      - name: funditest
+        files:
+          - name: main.go
+            template: main.go.tmpl
+    """
+    And a ".values.yml" file with the following contents
+    """
+    main.go.tmpl: {}
+    """
+    And a "blueprints/go-service/main.go.tmpl" file with the following contents
+    """
+    // Package main is {{ .name }}.
+    package main
+    """
+    When I execute the cli command
+    """
+    git init --quiet testdata/blueprints
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    git -C testdata/blueprints add .
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    git -C testdata/blueprints -c user.name=fundi -c user.email=fundi@example.com commit --quiet --allow-empty -m templates
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    git -C testdata/blueprints tag --force v1.0.0
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    rm -rf /tmp/fundi-blueprints.git
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    git clone --quiet --bare testdata/blueprints /tmp/fundi-blueprints.git
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/main.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    // Package main is funditest.
+    package main
+    """
+
+  Scenario: generate files from templates and values in an archive
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "./testdata/bundle.tar.gz//bundle/go-service"
+      values: "./testdata/bundle.tar.gz//bundle/values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: main.go
+            template: main.go.tmpl
+    """
+    And a "bundle/go-service/main.go.tmpl" file with the following contents
+    """
+    package {{ .package }}
+    """
+    And a "bundle/values.yml" file with the following contents
+    """
+    main.go.tmpl:
+      package: main
+    """
+    When I execute the cli command
+    """
+    tar -czf testdata/bundle.tar.gz -C testdata bundle
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi generate -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/main.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    package main
+    """
+
+  Scenario: generate files offline from templates in the cache
+    Given I have the following configuration
+    """
+    metadata:
+      output: "."
+      templates: "git+file:///tmp/fundi-offline-blueprints.git//go-service?ref=v1.0.0"
+      values: "./testdata/.values.yml"
+    directories:
+      - name: funditest
+        files:
+          - name: main.go
+            template: main.go.tmpl
+    """
+    And a ".values.yml" file with the following contents
+    """
+    main.go.tmpl:
+      package: main
+    """
+    And a "offline/go-service/main.go.tmpl" file with the following contents
+    """
+    package {{ .package }}
+    """
+    When I execute the cli command
+    """
+    git init --quiet testdata/offline
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    git -C testdata/offline add .
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    git -C testdata/offline -c user.name=fundi -c user.email=fundi@example.com commit --quiet --allow-empty -m templates
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    git -C testdata/offline tag --force v1.0.0
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    rm -rf /tmp/fundi-offline-blueprints.git
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    git clone --quiet --bare testdata/offline /tmp/fundi-offline-blueprints.git
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi generate --dry-run -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    rm -rf /tmp/fundi-offline-blueprints.git
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi generate --offline -f {{.ConfigFile}}
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/main.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    package main
+    """
+    When I execute the cli command
+    """
+    fundi cache list
+    """
+    Then I must get an exit code 0
+
+  Scenario: generate a new project from a blueprint in a registry
+    Given a "registry.yaml" file with the following contents
+    """
+    blueprints:
+      go-service:
+        "2.0":
+          config: go-service/2.0/.fundi.yaml
+        "2.1":
+          config: go-service/2.1/.fundi.yaml
+    """
+    And a "go-service/2.1/.fundi.yaml" file with the following contents
+    """
+    metadata:
+      templates: ./templates
+      values: ./values.yml
+    directories:
+      - name: cmd
+        files:
+          - name: main.go
+            template: main.go.tmpl
+    """
+    And a "go-service/2.1/values.yml" file with the following contents
+    """
+    main.go.tmpl:
+      version: "2.1"
+    """
+    And a "go-service/2.1/templates/main.go.tmpl" file with the following contents
+    """
+    // Package main is generated from go-service {{ .version }}.
+    package main
+    """
+    When I execute the cli command
+    """
+    fundi new go-service funditest --registry testdata/registry.yaml
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/cmd/main.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    // Package main is generated from go-service 2.1.
+    package main
+    """
+    When I execute the cli command
+    """
+    fundi new go-service@3.0 funditest --registry testdata/registry.yaml
+    """
+    Then I must get an exit code 1
+    And I must get a command output
+    """
+    blueprint go-service has no version 3.0, use one of 2.0, 2.1
+    """
+
+  Scenario: generate a new project from a blueprint built into fundi
+    When I execute the cli command
+    """
+    fundi generate --dry-run --blueprint go-cli
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    fundi new go-library funditest --var name=funditest --var module=github.com/acme/funditest
+    """
+    Then I must get an exit code 0
+    When I execute the cli command
+    """
+    cat funditest/funditest.go
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    package funditest
+
+    // Greet returns a greeting for name.
+    func Greet(name string) string {
+    	return "Hello, " + name + "!"
+    }
+    """
+    When I execute the cli command
+    """
+    head -1 funditest/go.mod
+    """
+    Then I must get an exit code 0
+    And I must get a command output
+    """
+    module github.com/acme/funditest
+    """
+    When I execute the cli command
+    """
+    fundi new go-api funditest
+    """
+    Then I must get an exit code 1
+    And I must get a command output
+    """
+    blueprint go-api is not built into fundi, use one of go-cli, go-library, go-service, or set a registry with --registry or FUNDI_REGISTRY
+    """
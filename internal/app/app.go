@@ -8,13 +8,17 @@ import (
 	"github.com/goava/di"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/spf13/afero"
-
-	"github.com/kasulani/go-fundi/internal/generate"
 )
 
 type (
 	config struct {
-		LogLevel string `envconfig:"LOG_LEVEL" default:"debug"`
+		LogLevel   string `envconfig:"LOG_LEVEL" default:"debug"`
+		OnConflict string `envconfig:"FUNDI_ON_CONFLICT" default:"skip"`
+		Strict     bool   `envconfig:"FUNDI_STRICT" default:"false"`
+		CacheDir   string `envconfig:"FUNDI_CACHE_DIR"`
+		Offline    bool   `envconfig:"FUNDI_OFFLINE" default:"false"`
+		Registry   string `envconfig:"FUNDI_REGISTRY"`
+		DryRun     bool   `ignored:"true"`
 	}
 )
 
@@ -29,12 +33,17 @@ func Container() *di.Container {
 		di.Provide(newConfig),
 		di.Provide(afero.NewOsFs),
 		di.Provide(newFileReader),
-		di.Provide(generate.NewProjectUseCase),
+		di.Provide(newCache),
+		di.Provide(newTemplatesResolver),
+		di.Provide(newBlueprintResolver),
 		di.Provide(newRootCommand),
 		di.Invoke(registerSubCommands),
 		di.Provide(newGenerateProjectCommand, di.As(new(SubCommand))),
-		di.Provide(newDirectoryCreator, di.As(new(generate.DirectoryStructureCreator))),
-		di.Provide(newFilesCreator, di.As(new(generate.FilesCreator))),
+		di.Provide(newNewProjectCommand, di.As(new(SubCommand))),
+		di.Provide(newDiffProjectCommand, di.As(new(SubCommand))),
+		di.Provide(newUpdateProjectCommand, di.As(new(SubCommand))),
+		di.Provide(newCleanProjectCommand, di.As(new(SubCommand))),
+		di.Provide(newCacheCommand, di.As(new(SubCommand))),
 	)
 
 	if err != nil {
@@ -1,11 +1,30 @@
 package app
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"errors"
+	"fmt"
+	"go/format"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kasulani/go-fundi/internal/generate"
 )
 
 func TestReadYAMLFile(t *testing.T) {
@@ -36,9 +55,14 @@ metadata:
   values: "./values.yml"
 directories:
   - name: project_name
+    when: "{{ .withDocs }}"
     files:
       - name: README.md
         template: readme.md.tmpl
+        skip: true
+        foreach: domains
+        values:
+          title: Domains
 `),
 		},
 	}
@@ -65,7 +89,1934 @@ directories:
 				assert.Len(t, cfg.Directories[0].Files, 1)
 				assert.Equal(t, "README.md", cfg.Directories[0].Files[0].Name)
 				assert.Equal(t, "readme.md.tmpl", cfg.Directories[0].Files[0].Template)
+				assert.True(t, cfg.Directories[0].Files[0].Skip)
+				assert.Equal(t, "domains", cfg.Directories[0].Files[0].ForEach)
+				assert.Equal(t, map[string]any{"title": "Domains"}, cfg.Directories[0].Files[0].Values)
+				assert.False(t, cfg.Directories[0].Skip)
+				assert.Equal(t, "{{ .withDocs }}", cfg.Directories[0].When)
+			}
+		})
+	}
+}
+
+func TestWorkspaceChanges(t *testing.T) {
+	base := afero.NewMemMapFs()
+	assert.NoError(t, base.MkdirAll("project/cmd", 0755))
+	assert.NoError(t, afero.WriteFile(base, "project/README.md", []byte("# project"), 0600))
+	assert.NoError(t, afero.WriteFile(base, "project/go.mod", []byte("module project"), 0600))
+
+	ws := newWorkspace(base)
+	assert.NoError(t, ws.MkdirAll("project/cmd", 0755))
+	assert.NoError(t, ws.MkdirAll("project/internal", 0755))
+	assert.NoError(t, afero.WriteFile(ws, "project/README.md", []byte("# generated project"), 0600))
+	assert.NoError(t, afero.WriteFile(ws, "project/go.mod", []byte("module project"), 0600))
+	assert.NoError(t, afero.WriteFile(ws, "project/internal/doc.go", []byte("package internal"), 0600))
+
+	actual, err := ws.changes("project")
+
+	assert.NoError(t, err)
+	assert.Equal(t, changes{
+		{path: "project/README.md", action: actionOverwrite},
+		{path: "project/go.mod", action: actionUnchanged},
+		{path: "project/internal", action: actionCreate, isDir: true},
+		{path: "project/internal/doc.go", action: actionCreate},
+	}, actual)
+
+	assert.NoError(t, ws.MkdirAll("other", 0755))
+	assert.NoError(t, afero.WriteFile(ws, "other/doc.go", []byte("package other"), 0600))
+
+	actual, err = ws.changes("./other")
+
+	assert.NoError(t, err)
+	assert.Equal(t, changes{
+		{path: "other", action: actionCreate, isDir: true},
+		{path: "other/doc.go", action: actionCreate},
+	}, actual)
+
+	current, err := afero.ReadFile(base, "project/README.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "# project", string(current))
+
+	exists, err := afero.Exists(base, "project/internal")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestWorkspaceCommit(t *testing.T) {
+	tests := map[string]struct {
+		conflictingFile bool
+		strayFile       bool
+		expectedErr     error
+		expectedReadme  string
+	}{
+		"when every step succeeds, the base holds the workspace": {
+			expectedReadme: "# generated project",
+		},
+		"when a step fails, the base is left as it was": {
+			conflictingFile: true,
+			expectedErr:     errors.New("failed to commit the project: failed to create directory project/cmd: mkdir project/cmd: file already exists"),
+			expectedReadme:  "# project",
+		},
+		"when a file is outside of the project, the base is left as it was": {
+			strayFile:      true,
+			expectedErr:    errors.New("failed to commit the project: /escape/x.txt is outside of project"),
+			expectedReadme: "# project",
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			base := afero.NewMemMapFs()
+			assert.NoError(t, afero.WriteFile(base, "project/README.md", []byte("# project"), 0600))
+
+			if testCase.conflictingFile {
+				assert.NoError(t, afero.WriteFile(base, "project/cmd", []byte("not a directory"), 0600))
+			}
+
+			ws, err := newStagingWorkspace(base)
+			assert.NoError(t, err)
+
+			assert.NoError(t, ws.layer.MkdirAll("project/cmd", 0755))
+			assert.NoError(t, ws.layer.MkdirAll("project/internal", 0755))
+			assert.NoError(t, afero.WriteFile(ws.layer, "project/README.md", []byte("# generated project"), 0600))
+			assert.NoError(t, afero.WriteFile(ws.layer, "project/internal/doc.go", []byte("package internal"), 0600))
+
+			if testCase.strayFile {
+				assert.NoError(t, afero.WriteFile(ws.layer, "project/../escape/x.txt", []byte("escaped"), 0600))
+			}
+
+			err = ws.commit("project")
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+			}
+
+			readme, err := afero.ReadFile(base, "project/README.md")
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedReadme, string(readme))
+
+			exists, err := afero.Exists(base, "project/internal/doc.go")
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedErr == nil, exists)
+
+			assert.NoError(t, ws.discard())
+
+			exists, err = afero.Exists(base, ws.staging)
+			assert.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestWorkspaceDiff(t *testing.T) {
+	base := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(base, "project/README.md", []byte("# project\n"), 0600))
+	assert.NoError(t, afero.WriteFile(base, "project/go.mod", []byte("module project\n"), 0600))
+
+	ws := newWorkspace(base)
+	assert.NoError(t, ws.MkdirAll("project/internal", 0755))
+	assert.NoError(t, afero.WriteFile(ws, "project/README.md", []byte("# generated project\n"), 0600))
+	assert.NoError(t, afero.WriteFile(ws, "project/go.mod", []byte("module project\n"), 0600))
+	assert.NoError(t, afero.WriteFile(ws, "project/internal/doc.go", []byte("package internal\n"), 0600))
+
+	tests := map[string]struct {
+		change       *change
+		expectedDiff string
+	}{
+		"when the file is overwritten, return a diff from the file on disk": {
+			change: &change{path: "project/README.md", action: actionOverwrite},
+			expectedDiff: "--- a/project/README.md\n+++ b/project/README.md\n@@ -1 +1 @@\n" +
+				"-# project\n+# generated project\n",
+		},
+		"when the file is created, return a diff from an empty file": {
+			change:       &change{path: "project/internal/doc.go", action: actionCreate},
+			expectedDiff: "--- /dev/null\n+++ b/project/internal/doc.go\n@@ -0,0 +1 @@\n+package internal\n",
+		},
+		"when the directory is created, return a line for it": {
+			change:       &change{path: "project/internal", action: actionCreate, isDir: true},
+			expectedDiff: "Only in generated project: project/internal/\n",
+		},
+		"when the file is unchanged, return no diff": {
+			change: &change{path: "project/go.mod", action: actionUnchanged},
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			diff, err := ws.diff(testCase.change)
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedDiff, diff)
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := map[string]struct {
+		base              string
+		ours              string
+		theirs            string
+		expectedMerged    string
+		expectedConflicts int
+	}{
+		"when only the template changed, take the new render": {
+			base:           "package main\n\nfunc main() {}\n",
+			ours:           "package main\n\nfunc main() {}\n",
+			theirs:         "// Package main runs the app.\npackage main\n\nfunc main() {}\n",
+			expectedMerged: "// Package main runs the app.\npackage main\n\nfunc main() {}\n",
+		},
+		"when only the file changed, keep the edits": {
+			base:           "package main\n\nfunc main() {}\n",
+			ours:           "package main\n\nfunc main() {\n\trun()\n}\n",
+			theirs:         "package main\n\nfunc main() {}\n",
+			expectedMerged: "package main\n\nfunc main() {\n\trun()\n}\n",
+		},
+		"when both changed different lines, merge them": {
+			base:           "package main\n\nimport \"fmt\"\n\nfunc main() {}\n",
+			ours:           "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println()\n}\n",
+			theirs:         "// Package main runs the app.\npackage main\n\nimport \"fmt\"\n\nfunc main() {}\n",
+			expectedMerged: "// Package main runs the app.\npackage main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println()\n}\n",
+		},
+		"when both made the same change, take it once": {
+			base:           "a\nb\nc\n",
+			ours:           "a\nB\nc\n",
+			theirs:         "a\nB\nc\n",
+			expectedMerged: "a\nB\nc\n",
+		},
+		"when both changed the same lines differently, mark the conflict": {
+			base:              "a\nb\nc\n",
+			ours:              "a\nours\nc\n",
+			theirs:            "a\ntheirs\nc\n",
+			expectedMerged:    "a\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\nc\n",
+			expectedConflicts: 1,
+		},
+		"when there is no base, mark every difference as a conflict": {
+			ours:              "ours",
+			theirs:            "theirs\n",
+			expectedMerged:    "<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\n",
+			expectedConflicts: 1,
+		},
+		"when only the template of a binary file changed, take the new copy": {
+			base:           "\x89PNG\x00\x01",
+			ours:           "\x89PNG\x00\x01",
+			theirs:         "\x89PNG\x00\x02",
+			expectedMerged: "\x89PNG\x00\x02",
+		},
+		"when both versions of a binary file changed, keep ours as a conflict": {
+			base:              "\x89PNG\x00\x01",
+			ours:              "\x89PNG\x00\x03",
+			theirs:            "\x89PNG\x00\x02",
+			expectedMerged:    "\x89PNG\x00\x03",
+			expectedConflicts: 1,
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			merged, conflicts := merge([]byte(testCase.base), []byte(testCase.ours), []byte(testCase.theirs))
+
+			assert.Equal(t, testCase.expectedMerged, string(merged))
+			assert.Equal(t, testCase.expectedConflicts, conflicts)
+		})
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	tests := map[string]struct {
+		expectedErr    error
+		expectedPolicy conflictPolicy
+		value          string
+	}{
+		"when the value names a policy, return the policy": {
+			expectedPolicy: conflictBackup,
+			value:          "backup",
+		},
+		"when the value is true, overwrite the file": {
+			expectedPolicy: conflictOverwrite,
+			value:          "true",
+		},
+		"when the value is false, skip the file": {
+			expectedPolicy: conflictSkip,
+			value:          "false",
+		},
+		"when the value is unknown, return an error": {
+			expectedErr: errors.New(`unknown conflict policy "replace", use one of skip, overwrite, fail, backup, prompt`),
+			value:       "replace",
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy, err := parseConflictPolicy(testCase.value)
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedPolicy, policy)
+			}
+		})
+	}
+}
+
+func TestResolveConflict(t *testing.T) {
+	tests := map[string]struct {
+		expectedErr      error
+		expectedWrite    bool
+		expectedBackup   bool
+		existingContents []byte
+		policy           conflictPolicy
+	}{
+		"when the file does not exist, write it": {
+			expectedWrite: true,
+			policy:        conflictSkip,
+		},
+		"when the file already holds the generated contents, write it": {
+			expectedWrite:    true,
+			existingContents: []byte("generated"),
+			policy:           conflictFail,
+		},
+		"when the file differs and the policy is skip, keep it": {
+			existingContents: []byte("edited"),
+			policy:           conflictSkip,
+		},
+		"when the file differs and the policy is overwrite, write it": {
+			expectedWrite:    true,
+			existingContents: []byte("edited"),
+			policy:           conflictOverwrite,
+		},
+		"when the file differs and the policy is backup, back it up and write it": {
+			expectedWrite:    true,
+			expectedBackup:   true,
+			existingContents: []byte("edited"),
+			policy:           conflictBackup,
+		},
+		"when the file differs and the policy is fail, return an error": {
+			expectedErr:      errors.New("file project/README.md already exists"),
+			existingContents: []byte("edited"),
+			policy:           conflictFail,
+		},
+		"when the file differs, the policy is prompt and there is no terminal, return an error": {
+			expectedErr: errors.New("file project/README.md already exists, and there is no terminal to ask " +
+				"whether to overwrite it, set --on-conflict to another policy"),
+			existingContents: []byte("edited"),
+			policy:           conflictPrompt,
+		},
+	}
+
+	terminal := isTerminal
+	isTerminal = func(int) bool { return false }
+
+	t.Cleanup(func() { isTerminal = terminal })
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if testCase.existingContents != nil {
+				assert.NoError(t, afero.WriteFile(fs, "project/README.md", testCase.existingContents, 0600))
+			}
+
+			creator := &filesCreator{fs: fs, cfg: &config{}, quiet: true}
+			write, err := creator.resolveConflict("project/README.md", []byte("generated"), testCase.policy)
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedWrite, write)
+			}
+
+			backup, err := afero.ReadFile(fs, "project/README.md.bak")
+			switch testCase.expectedBackup {
+			case true:
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.existingContents, backup)
+			case false:
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestResolveConflictInDryRun(t *testing.T) {
+	for _, policy := range conflictPolicies {
+		t.Run(string(policy), func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			assert.NoError(t, afero.WriteFile(fs, "project/README.md", []byte("edited"), 0600))
+
+			ws := newWorkspace(fs)
+			creator := &filesCreator{fs: ws, cfg: &config{DryRun: true}, quiet: true}
+
+			write, err := creator.resolveConflict("project/README.md", []byte("generated"), policy)
+			assert.NoError(t, err)
+			assert.True(t, write)
+			assert.NoError(t, afero.WriteFile(ws, "project/README.md", []byte("generated"), 0600))
+
+			changes, err := ws.changes("project")
+			assert.NoError(t, err)
+
+			action := fmt.Sprintf("%s (%s)", actionConflict, policy)
+			if policy == conflictOverwrite {
+				action = actionOverwrite
+			}
+
+			assert.Equal(t, action, changes[0].action)
+
+			exists, err := afero.Exists(fs, "project/README.md.bak")
+			assert.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestWriteLockFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "templates/README.md.tmpl", []byte("# {{ .name }}"), 0600))
+	assert.NoError(t, afero.WriteFile(fs, "templates/values.yml", []byte("README.md.tmpl:\n  name: fundi\n"), 0600))
+	assert.NoError(t, afero.WriteFile(fs, "fundi.yml", []byte(`
+metadata:
+  output: "out"
+  templates: "templates"
+  values: "templates/values.yml"
+  variables:
+    withDocs: true
+directories:
+  - name: project
+    files:
+      - name: README.md
+        template: README.md.tmpl
+    directories:
+      - name: cmd
+`), 0600))
+
+	yamlFile, err := newFileReader(fs).readYAMLFile("fundi.yml")
+	assert.NoError(t, err)
+
+	project, err := newProjectUseCase(fs, &config{OnConflict: string(conflictSkip)}, true).
+		ScaffoldProject(context.Background(), yamlFile.toConfigurationFile())
+	assert.NoError(t, err)
+
+	assert.NoError(t, afero.WriteFile(fs, "out/.fundi.lock", []byte("directories:\n  - project/docs\n"), 0600))
+	assert.NoError(t, writeLockFile(fs, yamlFile, project, []string{"project/cmd"}))
+
+	data, err := afero.ReadFile(fs, "out/.fundi.lock")
+	assert.NoError(t, err)
+
+	var lock lockFile
+	assert.NoError(t, yaml.Unmarshal(data, &lock))
+	assert.Equal(t, "fundi.yml", lock.Config.Path)
+	assert.Equal(t, yamlFile.hash, lock.Config.SHA256)
+	assert.Equal(t, map[string]any{"withDocs": true}, lock.Variables)
+	assert.Equal(t, "templates", lock.Templates.Source)
+	assert.NotEmpty(t, lock.Templates.Version)
+	assert.Equal(t, []string{"project/cmd", "project/docs"}, lock.Directories)
+	assert.Equal(t, []*lockedFile{{Path: "project/README.md", SHA256: digest([]byte("# fundi"))}}, lock.Files)
+}
+
+func TestCleanProject(t *testing.T) {
+	tests := map[string]struct {
+		edit          bool
+		force         bool
+		existed       bool
+		expectedKept  []string
+		expectedGone  []string
+		expectedFiles []*lockedFile
+	}{
+		"when no file was edited, remove the project and the lock file": {
+			expectedGone: []string{"out/project", "out/.fundi", "out/.fundi.lock"},
+		},
+		"when a file was edited, keep it and list it in the lock file": {
+			edit:          true,
+			expectedKept:  []string{"out/project/README.md", "out/.fundi/generated/project/README.md"},
+			expectedGone:  []string{"out/project/cmd", "out/.fundi/generated/project/cmd"},
+			expectedFiles: []*lockedFile{{Path: "project/README.md", SHA256: digest([]byte("# project"))}},
+		},
+		"when a file was edited and the clean is forced, remove it anyway": {
+			edit:         true,
+			force:        true,
+			expectedGone: []string{"out/project", "out/.fundi", "out/.fundi.lock"},
+		},
+		"when a directory was there before the project was generated, keep it": {
+			existed:      true,
+			expectedKept: []string{"out/project"},
+			expectedGone: []string{"out/project/README.md", "out/project/cmd", "out/.fundi", "out/.fundi.lock"},
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			generated := map[string]string{"project/README.md": "# project", "project/cmd/main.go": "package main"}
+			lock := &lockFile{Directories: []string{"project", "project/cmd"}}
+
+			if testCase.existed {
+				lock.Directories = []string{"project/cmd"}
+			}
+
+			for _, path := range []string{"project/README.md", "project/cmd/main.go"} {
+				assert.NoError(t, fs.MkdirAll(filepath.Dir(filepath.Join("out", path)), 0755))
+				assert.NoError(t, afero.WriteFile(fs, filepath.Join("out", path), []byte(generated[path]), 0600))
+				assert.NoError(t, writeRecord(fs, "out", path, []byte(generated[path])))
+				lock.Files = append(lock.Files, &lockedFile{Path: path, SHA256: digest([]byte(generated[path]))})
+			}
+
+			assert.NoError(t, lock.write(fs, "out"))
+
+			if testCase.edit {
+				assert.NoError(t, afero.WriteFile(fs, "out/project/README.md", []byte("# edited"), 0600))
+			}
+
+			assert.NoError(t, cleanProject(fs, "out", testCase.force))
+
+			for _, path := range testCase.expectedKept {
+				exists, err := afero.Exists(fs, path)
+				assert.NoError(t, err)
+				assert.True(t, exists, path)
+			}
+
+			for _, path := range testCase.expectedGone {
+				exists, err := afero.Exists(fs, path)
+				assert.NoError(t, err)
+				assert.False(t, exists, path)
+			}
+
+			exists, err := afero.DirExists(fs, "out")
+			assert.NoError(t, err)
+			assert.True(t, exists)
+
+			if testCase.expectedFiles != nil {
+				actual, err := readLockFile(fs, "out")
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedFiles, actual.Files)
+			}
+		})
+	}
+}
+
+func TestCleanOutput(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "fundi.yml", []byte("metadata:\n  output: from-config\n"), 0644))
+
+	output, err := cleanOutput(newFileReader(fs), "fundi.yml", []string{"from-args"})
+	assert.NoError(t, err)
+	assert.Equal(t, "from-args", output)
+
+	output, err = cleanOutput(newFileReader(fs), "fundi.yml", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "from-config", output)
+}
+
+func TestNewTemplateError(t *testing.T) {
+	tests := map[string]struct {
+		text          string
+		options       []string
+		expectedError string
+	}{
+		"when the template fails to parse, locate the line": {
+			text:          "package main\n\n{{ .package",
+			expectedError: "cmd/main.go: main.go.tmpl:3: unclosed action",
+		},
+		"when the template fails to execute, locate the line, the column and the values key": {
+			text:          "package main\n\nvar port = {{ .server.port }}",
+			options:       []string{"missingkey=error"},
+			expectedError: `cmd/main.go: main.go.tmpl:3:21: map has no entry for key "server" (values key .server)`,
+		},
+		"when a nested key is missing, name its first segment that is missing": {
+			text:          "{{ .missing.deep }}",
+			options:       []string{"missingkey=error"},
+			expectedError: `cmd/main.go: main.go.tmpl:1:11: map has no entry for key "missing" (values key .missing)`,
+		},
+		"when a nested key is evaluated on an empty value, name the empty value": {
+			text:          "{{ .empty.deep }}",
+			expectedError: "cmd/main.go: main.go.tmpl:1:9: nil pointer evaluating interface {}.deep (values key .empty)",
+		},
+		"when the last segment of a nested key is missing, name the whole key": {
+			text:          "{{ .database.port }}",
+			options:       []string{"missingkey=error"},
+			expectedError: `cmd/main.go: main.go.tmpl:1:12: map has no entry for key "port" (values key .database.port)`,
+		},
+		"when the template fails in a function, locate it without a values key": {
+			text:          `{{ index .ports 2 }}`,
+			expectedError: "cmd/main.go: main.go.tmpl:1:3: error calling index: index out of range: 2",
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := template.New("main.go.tmpl").Option(testCase.options...).Parse(testCase.text)
+			if err == nil {
+				err = tmpl.Execute(new(strings.Builder), map[string]any{
+					"ports":    []int{8080},
+					"database": map[string]any{"host": "localhost"},
+					"empty":    nil,
+				})
+			}
+
+			assert.EqualError(t, newTemplateError("cmd/main.go", "main.go.tmpl", err), testCase.expectedError)
+		})
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	errs := templateErrors{
+		{file: "README.md", template: "README.md.tmpl", line: 1, message: "unclosed action"},
+		{file: "cmd/main.go", template: "main.go.tmpl", line: 3, column: 7, key: ".package", message: "nil pointer"},
+	}
+
+	assert.EqualError(t, errs, "2 templates failed to render:\n"+
+		"  README.md: README.md.tmpl:1: unclosed action\n"+
+		"  cmd/main.go: main.go.tmpl:3:7: nil pointer (values key .package)")
+}
+
+func TestRenderFiles(t *testing.T) {
+	tests := map[string]struct {
+		cfg              *config
+		strictMetadata   bool
+		expectedErr      error
+		expectedContents string
+	}{
+		"when strict mode is off, render the missing value": {
+			cfg:              &config{},
+			expectedContents: "package <no value>",
+		},
+		"when strict mode is on, return an error naming the file and the key": {
+			cfg: &config{Strict: true},
+			expectedErr: errors.New("1 template failed to render:\n" +
+				`  cmd/main.go: main.go.tmpl:1:11: map has no entry for key "package" (values key .package)`),
+		},
+		"when the metadata is strict, return an error naming the file and the key": {
+			cfg:            &config{},
+			strictMetadata: true,
+			expectedErr: errors.New("1 template failed to render:\n" +
+				`  cmd/main.go: main.go.tmpl:1:11: map has no entry for key "package" (values key .package)`),
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			assert.NoError(t, afero.WriteFile(fs, "templates/main.go.tmpl", []byte("package {{ .package }}"), 0600))
+			assert.NoError(t, afero.WriteFile(fs, "values.yml", []byte("main.go.tmpl:\n  name: main\n"), 0600))
+
+			metadata := generate.NewMetadata(map[string]any{
+				generate.MetaDataTemplatesKey: "templates",
+				generate.MetaDataValuesKey:    "values.yml",
+				generate.MetaDataStrictKey:    testCase.strictMetadata,
+			})
+
+			creator := &filesCreator{fs: fs, cfg: testCase.cfg, quiet: true}
+			contents, err := creator.renderFiles(metadata, generate.FileTemplates{
+				"cmd/main.go": generate.NewFile("main.go", "main.go.tmpl"),
+			})
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedContents, string(contents["cmd/main.go"]))
 			}
 		})
 	}
 }
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := map[string]struct {
+		text             string
+		expectedErr      error
+		expectedRendered string
+	}{
+		"camelCase": {
+			text:             `{{ camelCase "HTTP server-config" }}`,
+			expectedRendered: "httpServerConfig",
+		},
+		"pascalCase": {
+			text:             `{{ pascalCase "user_account" }}`,
+			expectedRendered: "UserAccount",
+		},
+		"snakeCase": {
+			text:             `{{ snakeCase "HTTPServer2Config" }}`,
+			expectedRendered: "http_server2_config",
+		},
+		"kebabCase": {
+			text:             `{{ kebabCase "userAccount" }}`,
+			expectedRendered: "user-account",
+		},
+		"pluralize": {
+			text:             `{{ pluralize "category" }} {{ pluralize "Box" }} {{ pluralize "person" }} {{ pluralize "day" }}`,
+			expectedRendered: "categories Boxes people days",
+		},
+		"pluralize nouns that end in s, z, ie or is": {
+			text: `{{ pluralize "status" }} {{ pluralize "bus" }} {{ pluralize "quiz" }} {{ pluralize "movie" }} ` +
+				`{{ pluralize "analysis" }} {{ pluralize "size" }}`,
+			expectedRendered: "statuses buses quizzes movies analyses sizes",
+		},
+		"singularize": {
+			text:             `{{ singularize "categories" }} {{ singularize "Boxes" }} {{ singularize "people" }}`,
+			expectedRendered: "category Box person",
+		},
+		"singularize nouns that end in s, z, ie or is": {
+			text: `{{ singularize "statuses" }} {{ singularize "buses" }} {{ singularize "quizzes" }} ` +
+				`{{ singularize "movies" }} {{ singularize "analyses" }} {{ singularize "sizes" }} ` +
+				`{{ singularize "houses" }} {{ singularize "waltzes" }} {{ singularize "classes" }}`,
+			expectedRendered: "status bus quiz movie analysis size house waltz class",
+		},
+		"goIdentifier": {
+			text:             `{{ goIdentifier "my-service" }} {{ goIdentifier "3d" }} {{ goIdentifier "type" }}`,
+			expectedRendered: "my_service _3d type_",
+		},
+		"quote": {
+			text:             `{{ quote .name }}`,
+			expectedRendered: `"fundi"`,
+		},
+		"indent and nindent": {
+			text:             `a:{{ nindent 2 "b: 1\nc: 2" }}`,
+			expectedRendered: "a:\n  b: 1\n  c: 2",
+		},
+		"default": {
+			text:             `{{ .missing | default "sqlite" }} {{ .name | default "sqlite" }}`,
+			expectedRendered: "sqlite fundi",
+		},
+		"required": {
+			text:        `{{ required "the database is required" .database }}`,
+			expectedErr: errors.New(`template: funcs:1:3: executing "funcs" at <required "the database is required" .database>: error calling required: the database is required`),
+		},
+		"toYaml": {
+			text:             `{{ toYaml .ports }}`,
+			expectedRendered: "- 80\n- 443",
+		},
+		"toJson": {
+			text:             `{{ toJson .ports }}`,
+			expectedRendered: "[80,443]",
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := template.New("funcs").Funcs(templateFuncs()).Parse(testCase.text)
+			assert.NoError(t, err)
+
+			rendered := new(strings.Builder)
+			err = tmpl.Execute(rendered, map[string]any{"name": "fundi", "ports": []int{80, 443}})
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedRendered, rendered.String())
+			}
+		})
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	id, err := newUUID()
+
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+}
+
+func TestRenderFilesWithPartials(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "templates/_partials/license.tmpl", []byte("// (c) {{ .owner }}"), 0600))
+	assert.NoError(t, afero.WriteFile(fs, "templates/_partials/doc.tmpl",
+		[]byte(`{{ define "doc" }}// Package {{ .package }} is generated.{{ end }}`), 0600))
+	assert.NoError(t, afero.WriteFile(fs, "templates/main.go.tmpl",
+		[]byte("{{ template \"license\" . }}\n{{ template \"doc\" . }}\npackage {{ .package }}"), 0600))
+	assert.NoError(t, afero.WriteFile(fs, "values.yml",
+		[]byte("main.go.tmpl:\n  owner: fundi\n  package: main\n"), 0600))
+
+	metadata := generate.NewMetadata(map[string]any{
+		generate.MetaDataTemplatesKey: "templates",
+		generate.MetaDataValuesKey:    "values.yml",
+	})
+
+	creator := &filesCreator{fs: fs, cfg: &config{}, quiet: true}
+	contents, err := creator.renderFiles(metadata, generate.FileTemplates{
+		"cmd/main.go": generate.NewFile("main.go", "main.go.tmpl"),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "// (c) fundi\n// Package main is generated.\npackage main", string(contents["cmd/main.go"]))
+}
+
+func TestTemplateData(t *testing.T) {
+	output, err := filepath.Abs("out")
+	assert.NoError(t, err)
+
+	configFile, err := filepath.Abs("fundi.yml")
+	assert.NoError(t, err)
+
+	timestamp := time.Now()
+	metadata := generate.NewMetadata(map[string]any{
+		generate.MetaDataOutputKey:     "out",
+		generate.MetaDataConfigFileKey: "fundi.yml",
+	})
+	tests := map[string]struct {
+		values       map[string]any
+		fileValues   map[string]any
+		expectedData any
+	}{
+		"when the template has values, they override the variables": {
+			values: map[string]any{"service.go.tmpl": map[string]any{"package": "billing"}},
+			expectedData: map[string]any{
+				"package": "billing",
+				"Fundi": &templateContext{
+					Output:     output,
+					Path:       filepath.Join(output, "payments/service.go"),
+					Dir:        "payments",
+					ConfigFile: configFile,
+					Timestamp:  timestamp,
+				},
+			},
+		},
+		"when the file has values, they override the values of the template": {
+			values: map[string]any{"service.go.tmpl": map[string]any{
+				"package": "billing",
+				"server":  map[string]any{"host": "localhost", "port": 8080},
+			}},
+			fileValues: map[string]any{"package": "payments", "server": map[string]any{"port": 9090}},
+			expectedData: map[string]any{
+				"package": "payments",
+				"server":  map[string]any{"host": "localhost", "port": 9090},
+				"Fundi": &templateContext{
+					Output:     output,
+					Path:       filepath.Join(output, "payments/service.go"),
+					Dir:        "payments",
+					ConfigFile: configFile,
+					Timestamp:  timestamp,
+				},
+			},
+		},
+		"when the values of the template are not a map, pass them on as Values": {
+			values: map[string]any{"service.go.tmpl": []any{"billing"}},
+			expectedData: map[string]any{
+				"Values": []any{"billing"},
+				"Fundi": &templateContext{
+					Output:     output,
+					Path:       filepath.Join(output, "payments/service.go"),
+					Dir:        "payments",
+					ConfigFile: configFile,
+					Timestamp:  timestamp,
+				},
+			},
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			creator := &filesCreator{fs: afero.NewMemMapFs(), cfg: &config{}}
+
+			file := generate.NewFile("service.go", "service.go.tmpl").WithValues(testCase.fileValues)
+
+			data, err := creator.templateData(metadata, "payments/service.go", file, testCase.values, timestamp)
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedData, data)
+		})
+	}
+}
+
+func TestRenderFilesWithContent(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "values.yml", []byte(""), 0600))
+
+	metadata := generate.NewMetadata(map[string]any{
+		generate.MetaDataTemplatesKey: "templates",
+		generate.MetaDataValuesKey:    "values.yml",
+	})
+
+	creator := &filesCreator{fs: fs, cfg: &config{}, quiet: true}
+	contents, err := creator.renderFiles(metadata, generate.FileTemplates{
+		".gitignore": generate.NewFile(".gitignore", "").WithContent("/bin\n"),
+		"doc.go": generate.NewFile("doc.go", "").
+			WithContent("// Package {{ .package }} is generated.\npackage {{ .package }}\n").
+			WithValues(map[string]any{"package": "fundi"}),
+		"broken.go": generate.NewFile("broken.go", "").WithContent("package {{ .package"),
+	})
+
+	assert.EqualError(t, err, "1 template failed to render:\n  broken.go: broken.go:1: unclosed action")
+	assert.Nil(t, contents)
+
+	contents, err = creator.renderFiles(metadata, generate.FileTemplates{
+		".gitignore": generate.NewFile(".gitignore", "").WithContent("/bin\n"),
+		"doc.go": generate.NewFile("doc.go", "").
+			WithContent("// Package {{ .package }} is generated.\npackage {{ .package }}\n").
+			WithValues(map[string]any{"package": "fundi"}),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/bin\n", string(contents[".gitignore"]))
+	assert.Equal(t, "// Package fundi is generated.\npackage fundi\n", string(contents["doc.go"]))
+}
+
+func TestRenderFilesWithoutValuesFile(t *testing.T) {
+	fs := afero.NewBasePathFs(afero.NewOsFs(), t.TempDir())
+	assert.NoError(t, fs.MkdirAll("templates", 0755))
+	assert.NoError(t, afero.WriteFile(fs, "templates/main.go.tmpl", []byte("package {{ .package }}\n"), 0600))
+
+	metadata := generate.NewMetadata(map[string]any{generate.MetaDataTemplatesKey: "templates"})
+
+	creator := &filesCreator{fs: fs, cfg: &config{}, quiet: true}
+	contents, err := creator.renderFiles(metadata, generate.FileTemplates{
+		".gitignore": generate.NewFile(".gitignore", "").WithContent("/bin\n"),
+		"main.go":    generate.NewFile("main.go", "main.go.tmpl").WithValues(map[string]any{"package": "main"}),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/bin\n", string(contents[".gitignore"]))
+	assert.Equal(t, "package main\n", string(contents["main.go"]))
+}
+
+func TestCreateVerbatimFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	logo := []byte("\x89PNG\r\n\x1a\n\x00{{ .x }}")
+	workflow := []byte("run: echo ${{ github.sha }}\n")
+
+	assert.NoError(t, afero.WriteFile(fs, "templates/assets/logo.png", logo, 0600))
+	assert.NoError(t, afero.WriteFile(fs, "templates/ci.yml", workflow, 0600))
+	assert.NoError(t, afero.WriteFile(fs, "templates/gradlew", []byte("#!/bin/sh\n{{ .x }}\n"), 0755))
+	assert.NoError(t, afero.WriteFile(fs, "templates/README.md.tmpl", []byte("# {{ .project }}\n"), 0600))
+	assert.NoError(t, afero.WriteFile(fs, "values.yml", []byte("README.md.tmpl:\n  project: fundi\n"), 0600))
+
+	metadata := generate.NewMetadata(map[string]any{
+		generate.MetaDataOutputKey:    "out",
+		generate.MetaDataTemplatesKey: "templates",
+		generate.MetaDataValuesKey:    "values.yml",
+		generate.MetaDataCopyKey:      []string{"*.png", "gradle*"},
+	})
+
+	creator := &filesCreator{fs: fs, cfg: &config{OnConflict: "skip"}, quiet: true}
+	err := creator.CreateFiles(context.Background(), metadata, generate.FileTemplates{
+		"assets/logo.png":          generate.NewFile("logo.png", "assets/logo.png"),
+		".github/workflows/ci.yml": generate.NewFile("ci.yml", "ci.yml").WithVerbatim(true),
+		"gradlew":                  generate.NewFile("gradlew", "gradlew"),
+		"README.md":                generate.NewFile("README.md", "README.md.tmpl"),
+		".github/CODEOWNERS":       generate.NewFile("CODEOWNERS", "").WithContent("* {{ @owner }}").WithVerbatim(true),
+	})
+	assert.NoError(t, err)
+
+	for name, expected := range map[string]string{
+		"out/assets/logo.png":          string(logo),
+		"out/.github/workflows/ci.yml": string(workflow),
+		"out/gradlew":                  "#!/bin/sh\n{{ .x }}\n",
+		"out/README.md":                "# fundi\n",
+		"out/.github/CODEOWNERS":       "* {{ @owner }}",
+	} {
+		data, err := afero.ReadFile(fs, name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(data), name)
+	}
+
+	info, err := fs.Stat("out/gradlew")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	info, err = fs.Stat("out/README.md")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	_, err = isVerbatim(generate.NewMetadata(map[string]any{generate.MetaDataCopyKey: []string{"[*.png"}}),
+		generate.NewFile("logo.png", "logo.png"))
+	assert.EqualError(t, err, `invalid copy pattern "[*.png": syntax error in pattern`)
+}
+
+func TestParseMode(t *testing.T) {
+	tests := map[string]struct {
+		expectedErr  error
+		expectedMode os.FileMode
+		value        string
+	}{
+		"when the mode has a leading zero, return the mode": {
+			expectedMode: 0755,
+			value:        "0755",
+		},
+		"when the mode has no leading zero, return the mode": {
+			expectedMode: 0700,
+			value:        "700",
+		},
+		"when the mode has an octal prefix, return the mode": {
+			expectedMode: 0644,
+			value:        "0o644",
+		},
+		"when the mode is not octal, return an error": {
+			expectedErr: errors.New(`invalid mode "0799", use octal permissions such as 0755`),
+			value:       "0799",
+		},
+		"when the mode sets more than permissions, return an error": {
+			expectedErr: errors.New(`invalid mode "4755", use octal permissions such as 0755`),
+			value:       "4755",
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			mode, err := parseMode(testCase.value)
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedMode, mode)
+			}
+		})
+	}
+}
+
+func TestCreateWithModes(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, fs.MkdirAll("out/shared", 0750))
+	assert.NoError(t, afero.WriteFile(fs, "out/run.sh", []byte("#!/bin/sh\n"), 0755))
+	assert.NoError(t, afero.WriteFile(fs, "values.yml", []byte(""), 0600))
+
+	directories := &directoryCreator{fs: fs, quiet: true}
+	err := directories.CreateDirectoryStructure(context.Background(), "out", []string{"secrets/keys", "shared"},
+		generate.DirectoryModes{"secrets": "0700", "shared": "0700"})
+	assert.NoError(t, err)
+
+	metadata := generate.NewMetadata(map[string]any{
+		generate.MetaDataOutputKey:    "out",
+		generate.MetaDataTemplatesKey: "templates",
+		generate.MetaDataValuesKey:    "values.yml",
+	})
+
+	files := &filesCreator{fs: fs, cfg: &config{OnConflict: "overwrite"}, quiet: true}
+	err = files.CreateFiles(context.Background(), metadata, generate.FileTemplates{
+		"scripts/build.sh": generate.NewFile("build.sh", "").WithContent("#!/bin/sh\n").WithMode("0755"),
+		"run.sh":           generate.NewFile("run.sh", "").WithContent("#!/bin/sh\nexec go run .\n"),
+		"secrets/key.pem":  generate.NewFile("key.pem", "").WithMode("0600"),
+	})
+	assert.NoError(t, err)
+
+	for path, expected := range map[string]os.FileMode{
+		"out/secrets":          0700,
+		"out/secrets/keys":     0755,
+		"out/shared":           0750,
+		"out/scripts/build.sh": 0755,
+		"out/run.sh":           0755,
+		"out/secrets/key.pem":  0600,
+	} {
+		info, err := fs.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, info.Mode().Perm(), path)
+	}
+
+	err = directories.CreateDirectoryStructure(context.Background(), "out", []string{"private"},
+		generate.DirectoryModes{"private": "rwx"})
+	assert.EqualError(t, err, `invalid mode on directory private: invalid mode "rwx", use octal permissions such as 0755`)
+}
+
+func TestSymlinks(t *testing.T) {
+	base := afero.NewOsFs()
+	output := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(output, "scripts"), 0755))
+	assert.NoError(t, os.Symlink("old.sh", filepath.Join(output, "scripts", "latest.sh")))
+	assert.NoError(t, os.Symlink("same.sh", filepath.Join(output, "scripts", "current.sh")))
+
+	ws, err := newStagingWorkspace(base)
+	assert.NoError(t, err)
+
+	defer func() { assert.NoError(t, ws.discard()) }()
+
+	assert.NoError(t, ws.MkdirAll(filepath.Join(output, "bin"), 0755))
+
+	metadata := generate.NewMetadata(map[string]any{generate.MetaDataOutputKey: output})
+	creator := &filesCreator{fs: ws, cfg: &config{OnConflict: "overwrite"}, quiet: true}
+	err = creator.CreateFiles(context.Background(), metadata, generate.FileTemplates{
+		"bin/run":            generate.NewFile("run", "").WithSymlink("../scripts/run.sh"),
+		"scripts/latest.sh":  generate.NewFile("latest.sh", "").WithSymlink("new.sh"),
+		"scripts/current.sh": generate.NewFile("current.sh", "").WithSymlink("same.sh"),
+	})
+	assert.NoError(t, err)
+
+	changes, err := ws.changes(output)
+	assert.NoError(t, err)
+
+	listed := make([]string, 0, len(changes))
+	for _, c := range changes {
+		listed = append(listed, c.action+" "+strings.TrimPrefix(c.String(), output))
+	}
+
+	assert.Equal(t, []string{
+		"create /bin/",
+		"create /bin/run -> ../scripts/run.sh",
+		"unchanged /scripts/current.sh -> same.sh",
+		"overwrite /scripts/latest.sh -> new.sh",
+	}, listed)
+
+	assert.NoError(t, ws.commit(output))
+
+	target, err := os.Readlink(filepath.Join(output, "bin", "run"))
+	assert.NoError(t, err)
+	assert.Equal(t, "../scripts/run.sh", target)
+
+	target, err = os.Readlink(filepath.Join(output, "scripts", "latest.sh"))
+	assert.NoError(t, err)
+	assert.Equal(t, "new.sh", target)
+
+	lock := &lockFile{Directories: []string{"bin"}, Files: []*lockedFile{
+		{Path: "bin/run", Symlink: "../scripts/run.sh"},
+		{Path: "scripts/latest.sh", Symlink: "newer.sh"},
+	}}
+	assert.NoError(t, lock.write(base, output))
+	assert.NoError(t, cleanProject(base, output, false))
+
+	_, err = os.Lstat(filepath.Join(output, "bin"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = os.Lstat(filepath.Join(output, "scripts", "latest.sh"))
+	assert.NoError(t, err)
+}
+
+func TestParseGitSource(t *testing.T) {
+	tests := map[string]struct {
+		expectedErr    error
+		expectedSource *gitSource
+		source         string
+	}{
+		"when the source names a subdirectory and a ref, return them": {
+			expectedSource: &gitSource{
+				repository:   "https://example.com/org/blueprints.git",
+				subdirectory: "go-service",
+				ref:          "v2.3",
+			},
+			source: "git+https://example.com/org/blueprints.git//go-service?ref=v2.3",
+		},
+		"when the source names no ref, read the templates at HEAD": {
+			expectedSource: &gitSource{repository: "file:///srv/blueprints.git", ref: "HEAD"},
+			source:         "git+file:///srv/blueprints.git",
+		},
+		"when the source is not a URL, return an error": {
+			expectedErr: errors.New(
+				"invalid templates source git+blueprints, use a URL such as git+https://host/repo.git",
+			),
+			source: "git+blueprints",
+		},
+		"when the subdirectory leads out of the repository, return an error": {
+			expectedErr: errors.New(
+				"invalid templates source git+file:///srv/blueprints.git//../secrets, subdirectory ../secrets " +
+					"is outside the repository",
+			),
+			source: "git+file:///srv/blueprints.git//../secrets",
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			source, err := parseGitSource(testCase.source)
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedSource, source)
+			}
+		})
+	}
+}
+
+func TestResolveGitTemplates(t *testing.T) {
+	repository, tagged, head := newTestGitRepository(t)
+	resolver := &templatesResolver{cfg: &config{}, cache: &cache{directory: t.TempDir()}}
+
+	tests := map[string]struct {
+		expectedErr      error
+		expectedTemplate string
+		expectedVersion  string
+		ref              string
+	}{
+		"when the ref is a tag, read the templates at the tag": {
+			expectedTemplate: "package {{ .package }}\n",
+			expectedVersion:  tagged,
+			ref:              "?ref=v1.0.0",
+		},
+		"when the ref is a commit, read the templates at the commit": {
+			expectedTemplate: "package {{ .package }}\n",
+			expectedVersion:  tagged,
+			ref:              "?ref=" + tagged,
+		},
+		"when the ref is a branch, read the templates at its head": {
+			expectedTemplate: "// Package {{ .package }} is generated.\npackage {{ .package }}\n",
+			expectedVersion:  head,
+			ref:              "?ref=main",
+		},
+		"when there is no ref, read the templates at HEAD": {
+			expectedTemplate: "// Package {{ .package }} is generated.\npackage {{ .package }}\n",
+			expectedVersion:  head,
+		},
+		"when the ref does not exist, return an error": {
+			expectedErr: errors.New("failed to fetch templates from git+file://" + repository +
+				"//go-service?ref=v9: ref v9 does not exist"),
+			ref: "?ref=v9",
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			source := "git+file://" + repository + "//go-service" + testCase.ref
+			yamlFile := &yamlFile{Metadata: &metadata{Templates: source}}
+
+			err := resolver.resolveSources(context.Background(), yamlFile)
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedVersion, yamlFile.templatesVersion)
+
+				data, err := os.ReadFile(filepath.Join(yamlFile.templates(), "main.go.tmpl"))
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedTemplate, string(data))
+			}
+		})
+	}
+}
+
+// newTestGitRepository returns the path of a bare git repository with templates in a go-service directory, along
+// with the commit tagged v1.0.0 and the commit at the head of its main branch.
+func newTestGitRepository(t *testing.T) (string, string, string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	ctx := context.Background()
+	work, repository := t.TempDir(), filepath.Join(t.TempDir(), "blueprints.git")
+	template := filepath.Join(work, "go-service", "main.go.tmpl")
+
+	git := func(args ...string) string {
+		t.Helper()
+
+		args = append([]string{"-c", "user.name=fundi", "-c", "user.email=fundi@example.com"}, args...)
+		out, err := runGit(ctx, work, args...)
+		assert.NoError(t, err)
+
+		return out
+	}
+
+	git("init", "--quiet", "--initial-branch=main")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(template), 0755))
+	assert.NoError(t, os.WriteFile(template, []byte("package {{ .package }}\n"), 0600))
+	git("add", ".")
+	git("commit", "--quiet", "-m", "add the go-service templates")
+	git("tag", "v1.0.0")
+	tagged := git("rev-parse", "HEAD")
+
+	assert.NoError(t, os.WriteFile(template,
+		[]byte("// Package {{ .package }} is generated.\npackage {{ .package }}\n"), 0600))
+	git("commit", "--quiet", "-am", "document the package")
+	head := git("rev-parse", "HEAD")
+
+	_, err := runGit(ctx, "", "clone", "--quiet", "--bare", work, repository)
+	assert.NoError(t, err)
+
+	return repository, tagged, head
+}
+
+func TestResolveArchives(t *testing.T) {
+	files := map[string]string{
+		"blueprints/go-service/main.go.tmpl": "package {{ .package }}\n",
+		"blueprints/values.yml":              "main.go.tmpl: {}\n",
+	}
+	tarball, zipped := newTestArchive(t, ".tar.gz", files), newTestArchive(t, ".zip", files)
+	tarballSum, zippedSum := digest(tarball), digest(zipped)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blueprints.tar.gz":
+			_, _ = w.Write(tarball)
+		case "/blueprints.zip":
+			_, _ = w.Write(zipped)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "bundles/blueprints.tgz", tarball, 0644))
+
+	tests := map[string]struct {
+		expectedErr     error
+		expectedVersion string
+		metadata        *metadata
+	}{
+		"when the templates and values are in a downloaded tar.gz archive, read them from it": {
+			expectedVersion: tarballSum,
+			metadata: &metadata{
+				Templates: server.URL + "/blueprints.tar.gz//blueprints/go-service",
+				Values:    server.URL + "/blueprints.tar.gz//blueprints/values.yml",
+				SHA256:    checksums{Templates: tarballSum, Values: "sha256:" + tarballSum},
+			},
+		},
+		"when the templates are in a downloaded zip archive, read them from it": {
+			expectedVersion: zippedSum,
+			metadata: &metadata{
+				Templates: server.URL + "/blueprints.zip//blueprints/go-service",
+				Values:    server.URL + "/blueprints.zip//blueprints/values.yml",
+				SHA256:    checksums{Templates: zippedSum, Values: zippedSum},
+			},
+		},
+		"when the templates are in a local archive, read them from it without a checksum": {
+			expectedVersion: tarballSum,
+			metadata: &metadata{
+				Templates: "bundles/blueprints.tgz//blueprints/go-service",
+				Values:    "bundles/blueprints.tgz//blueprints/values.yml",
+			},
+		},
+		"when a downloaded archive has no checksum, return an error": {
+			expectedErr: errors.New("failed to fetch templates from " + server.URL + "/blueprints.zip: a downloaded " +
+				"archive needs a checksum, set its sha256 under metadata.sha256"),
+			metadata: &metadata{Templates: server.URL + "/blueprints.zip"},
+		},
+		"when an archive does not match its checksum, return an error": {
+			expectedErr: errors.New("failed to fetch templates from " + server.URL + "/blueprints.zip: checksum " +
+				"mismatch, expected sha256 " + tarballSum + " but got " + zippedSum),
+			metadata: &metadata{Templates: server.URL + "/blueprints.zip", SHA256: checksums{Templates: tarballSum}},
+		},
+		"when an archive can't be downloaded, return an error": {
+			expectedErr: errors.New("failed to fetch templates from " + server.URL + "/missing.zip: GET " +
+				server.URL + "/missing.zip: 404 Not Found"),
+			metadata: &metadata{Templates: server.URL + "/missing.zip", SHA256: checksums{Templates: zippedSum}},
+		},
+		"when a download is not an archive, return an error": {
+			expectedErr: errors.New("failed to fetch templates from " + server.URL + "/templates: invalid source " +
+				server.URL + "/templates, only .tar.gz, .tgz and .zip archives can be downloaded"),
+			metadata: &metadata{Templates: server.URL + "/templates"},
+		},
+		"when the values name a directory in the archive, return an error": {
+			expectedErr: errors.New("values source bundles/blueprints.tgz is a directory, use a source such as " +
+				"bundle.tar.gz//values.yml"),
+			metadata: &metadata{Templates: "./templates", Values: "bundles/blueprints.tgz"},
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			resolver := &templatesResolver{fs: fs, cfg: &config{}, cache: &cache{directory: t.TempDir()}}
+			yamlFile := &yamlFile{Metadata: testCase.metadata}
+
+			err := resolver.resolveSources(context.Background(), yamlFile)
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedVersion, yamlFile.templatesVersion)
+
+				project := yamlFile.filesystem(fs)
+
+				data, err := afero.ReadFile(project, filepath.Join(yamlFile.templates(), "main.go.tmpl"))
+				assert.NoError(t, err)
+				assert.Equal(t, "package {{ .package }}\n", string(data))
+
+				data, err = afero.ReadFile(project, yamlFile.values())
+				assert.NoError(t, err)
+				assert.Equal(t, "main.go.tmpl: {}\n", string(data))
+
+				exists, err := afero.Exists(fs, yamlFile.templates())
+				assert.NoError(t, err)
+				assert.False(t, exists, "the archive must not be unpacked onto the file system")
+			}
+		})
+	}
+}
+
+func TestUnpackArchiveRejectsEntriesOutsideIt(t *testing.T) {
+	archive := newTestArchive(t, ".tar.gz", map[string]string{"../escape.txt": "outside"})
+
+	err := unpackArchive(afero.NewMemMapFs(), "/cache/archives/escape", archive)
+
+	assert.EqualError(t, err, "archive entry ../escape.txt is outside the archive")
+}
+
+// newTestArchive returns a .tar.gz or .zip archive of files, by their paths.
+func newTestArchive(t *testing.T, extension string, files map[string]string) []byte {
+	t.Helper()
+
+	buffer := new(bytes.Buffer)
+
+	if extension == ".zip" {
+		writer := zip.NewWriter(buffer)
+		for name, contents := range files {
+			entry, err := writer.Create(name)
+			assert.NoError(t, err)
+			_, err = entry.Write([]byte(contents))
+			assert.NoError(t, err)
+		}
+
+		assert.NoError(t, writer.Close())
+
+		return buffer.Bytes()
+	}
+
+	compressed := gzip.NewWriter(buffer)
+	writer := tar.NewWriter(compressed)
+
+	for name, contents := range files {
+		assert.NoError(t, writer.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := writer.Write([]byte(contents))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, compressed.Close())
+
+	return buffer.Bytes()
+}
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			_, _ = w.Write([]byte("small"))
+		case "/large":
+			_, _ = w.Write([]byte("larger than the limit"))
+		case "/stalled":
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte("late"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, size := downloadClient, maxDownloadSize
+	downloadClient, maxDownloadSize = &http.Client{Timeout: 50 * time.Millisecond}, 8
+
+	defer func() { downloadClient, maxDownloadSize = client, size }()
+
+	tests := map[string]struct {
+		path         string
+		expectedData string
+		expectedErr  string
+	}{
+		"when the body is within the limit, return it": {
+			path:         "/small",
+			expectedData: "small",
+		},
+		"when the body is larger than the limit, return an error": {
+			path:        "/large",
+			expectedErr: "GET " + server.URL + "/large: larger than 8 bytes",
+		},
+		"when the server takes longer than the timeout, return an error": {
+			path:        "/stalled",
+			expectedErr: "Client.Timeout exceeded",
+		},
+		"when there is nothing at the location, return an error": {
+			path:        "/missing",
+			expectedErr: "GET " + server.URL + "/missing: 404 Not Found",
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := download(context.Background(), server.URL+testCase.path)
+
+			if testCase.expectedErr != "" {
+				assert.ErrorContains(t, err, testCase.expectedErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedData, string(data))
+		})
+	}
+}
+
+func TestResolveOffline(t *testing.T) {
+	repository, taggedCommit, _ := newTestGitRepository(t)
+	tarball := newTestArchive(t, ".tar.gz", map[string]string{"go-service/main.go.tmpl": "package {{ .package }}\n"})
+	configFile := []byte("metadata:\n  templates: ./templates\n")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blueprints.tar.gz":
+			_, _ = w.Write(tarball)
+		case "/.fundi.yaml":
+			_, _ = w.Write(configFile)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	gitSource := "git+file://" + repository + "//go-service?ref=v1.0.0"
+	archiveSource := server.URL + "/blueprints.tar.gz//go-service"
+	configSource := server.URL + "/.fundi.yaml"
+
+	resolve := func(resolver *templatesResolver) error {
+		for _, templates := range []string{gitSource, archiveSource} {
+			yamlFile := &yamlFile{Metadata: &metadata{Templates: templates, SHA256: checksums{Templates: digest(tarball)}}}
+			if err := resolver.resolveSources(context.Background(), yamlFile); err != nil {
+				return err
+			}
+		}
+
+		_, err := resolver.downloadConfigFile(context.Background(), configSource)
+
+		return err
+	}
+
+	cache := &cache{directory: t.TempDir()}
+	offline := &templatesResolver{fs: afero.NewMemMapFs(), cfg: &config{Offline: true}, cache: cache}
+
+	t.Run("when the cache is empty, fail offline", func(t *testing.T) {
+		assert.EqualError(t, resolve(offline), "failed to fetch templates from "+gitSource+": repository file://"+
+			repository+" is not in the cache, run without --offline to fetch it")
+
+		yamlFile := &yamlFile{Metadata: &metadata{Templates: archiveSource, SHA256: checksums{Templates: digest(tarball)}}}
+		assert.EqualError(t, offline.resolveSources(context.Background(), yamlFile), "failed to fetch templates from "+
+			archiveSource+": archive "+server.URL+"/blueprints.tar.gz is not in the cache, run without --offline to "+
+			"fetch it")
+
+		_, err := offline.downloadConfigFile(context.Background(), configSource)
+		assert.EqualError(t, err, "config file "+configSource+" is not in the cache, run without --offline to fetch it")
+	})
+
+	t.Run("when the cache holds everything, resolve offline without a network", func(t *testing.T) {
+		online := &templatesResolver{fs: afero.NewMemMapFs(), cfg: &config{}, cache: cache}
+		assert.NoError(t, resolve(online))
+
+		server.Close()
+		assert.NoError(t, os.RemoveAll(repository))
+
+		assert.NoError(t, resolve(offline))
+
+		tagged := &yamlFile{Metadata: &metadata{Templates: gitSource}}
+		assert.NoError(t, offline.resolveSources(context.Background(), tagged))
+		assert.Equal(t, taggedCommit, tagged.templatesVersion)
+
+		missing := &yamlFile{Metadata: &metadata{Templates: strings.Replace(gitSource, "v1.0.0", "v2.0.0", 1)}}
+		assert.EqualError(t, offline.resolveSources(context.Background(), missing), "failed to fetch templates from "+
+			missing.Metadata.Templates+": ref v2.0.0 is not in the cache, run without --offline to fetch it")
+	})
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	repository, tagged, _ := newTestGitRepository(t)
+	cache := &cache{directory: t.TempDir()}
+	resolver := &templatesResolver{cfg: &config{}, cache: cache}
+
+	assert.NoError(t, resolver.resolveSources(ctx, &yamlFile{Metadata: &metadata{
+		Templates: "git+file://" + repository + "?ref=v1.0.0",
+	}}))
+	assert.NoError(t, cache.store(archiveCacheDirectory, digest([]byte("archive")), "https://host/a.tar.gz",
+		[]byte("archive")))
+	assert.NoError(t, cache.store(configCacheDirectory, cacheKey("https://host/.fundi.yaml"),
+		"https://host/.fundi.yaml", []byte("config")))
+
+	data, err := cache.load(configCacheDirectory, cacheKey("https://host/.fundi.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "config", string(data))
+
+	data, err = cache.load(configCacheDirectory, cacheKey("https://host/missing.yaml"))
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	entries, err := cache.entries(ctx)
+	assert.NoError(t, err)
+
+	describe := func(entries []*cacheEntry) []string {
+		described := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			described = append(described, entry.kind+" "+entry.source+" "+entry.version)
+		}
+
+		return described
+	}
+
+	assert.Equal(t, []string{
+		"archive https://host/a.tar.gz " + digest([]byte("archive")),
+		"config https://host/.fundi.yaml " + digest([]byte("config")),
+		"git file://" + repository + " " + tagged,
+	}, describe(entries))
+
+	output := new(bytes.Buffer)
+	assert.NoError(t, printEntries(output, entries))
+	assert.Contains(t, output.String(), "KIND     SOURCE")
+
+	lastMonth := time.Now().Add(-30 * 24 * time.Hour)
+	for _, entry := range entries[1:] {
+		assert.NoError(t, os.Chtimes(entry.path, lastMonth, lastMonth))
+	}
+
+	removed, err := cache.prune(ctx, time.Now().Add(-7*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, describe(entries[1:]), describe(removed))
+
+	entries, err = cache.entries(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = os.Stat(filepath.Join(cache.directory, gitCacheDirectory))
+	assert.NoError(t, err)
+
+	repositories, err := readCacheDirectory(filepath.Join(cache.directory, gitCacheDirectory))
+	assert.NoError(t, err)
+	assert.Empty(t, repositories)
+
+	assert.NoError(t, cache.clear())
+
+	_, err = os.Stat(cache.directory)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGenerateWithoutCacheDirectory(t *testing.T) {
+	t.Setenv("HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "templates/main.go.tmpl", []byte("package {{ .package }}\n"), 0644))
+	assert.NoError(t, afero.WriteFile(fs, "values.yml", []byte("main.go.tmpl:\n  package: main\n"), 0644))
+	assert.NoError(t, afero.WriteFile(fs, "fundi.yml", []byte(`
+metadata:
+  output: /out
+  templates: templates
+  values: values.yml
+directories:
+  - name: cmd
+    files:
+      - name: main.go
+        template: main.go.tmpl
+`), 0644))
+
+	cfg := &config{OnConflict: string(conflictSkip)}
+	cache := newCache(cfg)
+	resolver := newTemplatesResolver(cfg, fs, cache)
+
+	yamlFile, err := readConfigFile(context.Background(), newFileReader(fs), resolver, "fundi.yml")
+	assert.NoError(t, err)
+
+	_, err = generateProject(context.Background(), cfg, yamlFile.filesystem(fs), yamlFile)
+	assert.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "/out/cmd/main.go")
+	assert.NoError(t, err)
+	assert.Equal(t, "package main\n", string(data))
+
+	_, err = cache.path()
+	assert.ErrorContains(t, err, "failed to find the cache directory, set FUNDI_CACHE_DIR")
+}
+
+func TestUpdateProjectTwice(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "templates/README.md.tmpl", []byte("# generated\n"), 0644))
+	assert.NoError(t, afero.WriteFile(fs, "fundi.yml", []byte(`
+metadata:
+  output: /out
+  templates: templates
+directories:
+  - name: project
+    files:
+      - name: README.md
+        template: README.md.tmpl
+`), 0644))
+
+	cfg := &config{OnConflict: string(conflictSkip)}
+	resolver := newTemplatesResolver(cfg, fs, newCache(cfg))
+
+	yamlFile, err := readConfigFile(context.Background(), newFileReader(fs), resolver, "fundi.yml")
+	assert.NoError(t, err)
+
+	_, err = generateProject(context.Background(), cfg, yamlFile.filesystem(fs), yamlFile)
+	assert.NoError(t, err)
+
+	assert.NoError(t, afero.WriteFile(fs, "/out/project/README.md", []byte("# edited\n"), 0644))
+	assert.NoError(t, afero.WriteFile(fs, "templates/README.md.tmpl", []byte("# regenerated\n"), 0644))
+
+	conflicted := conflictOursMarker + "\n# edited\n" + conflictMarker + "\n# regenerated\n" + conflictTheirsMarker + "\n"
+
+	for range 2 {
+		conflicts, err := updateProject(context.Background(), cfg, yamlFile.filesystem(fs), yamlFile)
+		assert.NoError(t, err)
+		assert.True(t, conflicts)
+
+		data, err := afero.ReadFile(fs, "/out/project/README.md")
+		assert.NoError(t, err)
+		assert.Equal(t, conflicted, string(data))
+
+		record, err := readRecord(fs, "/out", "project/README.md")
+		assert.NoError(t, err)
+		assert.Equal(t, "# generated\n", string(record))
+	}
+}
+
+func TestDiffProjectIgnoresFilePolicies(t *testing.T) {
+	for _, policy := range []string{"false", "skip", "fail", "backup", "prompt"} {
+		t.Run(fmt.Sprintf("when a file is set to overwrite: %s, diff it all the same", policy), func(t *testing.T) {
+			fs, yamlFile := generateWithFilePolicy(t, policy)
+			assert.NoError(t, afero.WriteFile(fs, "/out/project/README.md", []byte("# drifted\n"), 0644))
+
+			cfg := &config{OnConflict: string(conflictFail)}
+
+			differs, err := diffProject(context.Background(), cfg, yamlFile.filesystem(fs), yamlFile)
+			assert.NoError(t, err)
+			assert.True(t, differs)
+
+			exists, err := afero.Exists(fs, "/out/project/README.md"+backupExtension)
+			assert.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestUpdateProjectIgnoresFilePolicies(t *testing.T) {
+	for _, policy := range []string{"false", "skip", "fail", "backup", "prompt"} {
+		t.Run(fmt.Sprintf("when a file is set to overwrite: %s, update it all the same", policy), func(t *testing.T) {
+			fs, yamlFile := generateWithFilePolicy(t, policy)
+			assert.NoError(t, afero.WriteFile(fs, "templates/README.md.tmpl", []byte("# regenerated\n"), 0644))
+
+			cfg := &config{OnConflict: string(conflictFail)}
+
+			conflicts, err := updateProject(context.Background(), cfg, yamlFile.filesystem(fs), yamlFile)
+			assert.NoError(t, err)
+			assert.False(t, conflicts)
+
+			data, err := afero.ReadFile(fs, "/out/project/README.md")
+			assert.NoError(t, err)
+			assert.Equal(t, "# regenerated\n", string(data))
+
+			exists, err := afero.Exists(fs, "/out/project/README.md"+backupExtension)
+			assert.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+}
+
+// generateWithFilePolicy generates a project whose README.md has the per-file overwrite setting policy.
+func generateWithFilePolicy(t *testing.T, policy string) (afero.Fs, *yamlFile) {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "templates/README.md.tmpl", []byte("# generated\n"), 0644))
+	assert.NoError(t, afero.WriteFile(fs, "fundi.yml", []byte(fmt.Sprintf(`
+metadata:
+  output: /out
+  templates: templates
+directories:
+  - name: project
+    files:
+      - name: README.md
+        template: README.md.tmpl
+        overwrite: "%s"
+`, policy)), 0644))
+
+	cfg := &config{OnConflict: string(conflictSkip)}
+	resolver := newTemplatesResolver(cfg, fs, newCache(cfg))
+
+	yamlFile, err := readConfigFile(context.Background(), newFileReader(fs), resolver, "fundi.yml")
+	assert.NoError(t, err)
+
+	_, err = generateProject(context.Background(), cfg, yamlFile.filesystem(fs), yamlFile)
+	assert.NoError(t, err)
+
+	return fs, yamlFile
+}
+
+func TestResolveBlueprint(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"registry/index.yaml": `blueprints:
+  go-service:
+    "2.1":
+      config: go-service/2.1/.fundi.yaml
+    "2.10":
+      config: go-service/2.10/.fundi.yaml
+      templates: ./shared/templates
+  broken:
+    "1.0": {}
+`,
+		"registry/go-service/2.1/.fundi.yaml": "metadata:\n  output: ignored\n  templates: ./templates\n" +
+			"  values: ./values.yml\n",
+		"registry/go-service/2.10/.fundi.yaml": "metadata:\n  templates: ./templates\n  values: /etc/values.yml\n",
+	}
+
+	for name, contents := range files {
+		assert.NoError(t, afero.WriteFile(fs, name, []byte(contents), 0644))
+	}
+
+	tests := map[string]struct {
+		expectedErr       error
+		expectedTemplates string
+		expectedValues    string
+		reference         string
+		registry          string
+	}{
+		"when the reference has a version, read the config file of that version": {
+			expectedTemplates: "registry/go-service/2.1/templates",
+			expectedValues:    "registry/go-service/2.1/values.yml",
+			reference:         "go-service@2.1",
+			registry:          "registry/index.yaml",
+		},
+		"when the reference has no version, read the latest version": {
+			expectedTemplates: "registry/shared/templates",
+			expectedValues:    "/etc/values.yml",
+			reference:         "go-service",
+			registry:          "registry/index.yaml",
+		},
+		"when there is no registry and the blueprint is not built in, return an error": {
+			expectedErr: errors.New("blueprint go-api is not built into fundi, use one of go-cli, go-library, " +
+				"go-service, or set a registry with --registry or FUNDI_REGISTRY"),
+			reference: "go-api",
+		},
+		"when there is no registry and the reference has a version, return an error": {
+			expectedErr: errors.New("built-in blueprint go-cli has no versions, set a registry with --registry or " +
+				"FUNDI_REGISTRY to use go-cli@1.0"),
+			reference: "go-cli@1.0",
+		},
+		"when the blueprint is not in the registry, return an error": {
+			expectedErr: errors.New("blueprint go-cli is not in the registry"),
+			reference:   "go-cli@1.0",
+			registry:    "registry/index.yaml",
+		},
+		"when the version is not in the registry, return an error": {
+			expectedErr: errors.New("blueprint go-service has no version 3.0, use one of 2.1, 2.10"),
+			reference:   "go-service@3.0",
+			registry:    "registry/index.yaml",
+		},
+		"when the version names no config file, return an error": {
+			expectedErr: errors.New("blueprint broken@1.0 names no config file"),
+			reference:   "broken",
+			registry:    "registry/index.yaml",
+		},
+	}
+
+	for name, testCase := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &config{Registry: testCase.registry}
+			blueprints := &blueprintResolver{
+				cfg:      cfg,
+				reader:   &fileReader{fs: fs},
+				resolver: &templatesResolver{fs: fs, cfg: cfg, cache: &cache{directory: t.TempDir()}},
+			}
+
+			yamlFile, err := blueprints.resolveBlueprint(context.Background(), testCase.reference, "my-svc")
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+				assert.Equal(t, "my-svc", yamlFile.Metadata.Output)
+				assert.Equal(t, testCase.expectedTemplates, yamlFile.templates())
+				assert.Equal(t, testCase.expectedValues, yamlFile.values())
+			}
+		})
+	}
+}
+
+func TestResolveRemoteBlueprint(t *testing.T) {
+	tarball := newTestArchive(t, ".tar.gz", map[string]string{
+		"templates/main.go.tmpl": "package {{ .package }}\n",
+		"values.yml":             "main.go.tmpl: {}\n",
+	})
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blueprints/index.json":
+			_, _ = w.Write([]byte(`{"blueprints": {"go-service": {"2.1": {"config": "go-service/2.1/fundi.yaml", ` +
+				`"sha256": {"templates": "` + digest(tarball) + `", "values": "` + digest(tarball) + `"}}}}}`))
+		case "/blueprints/go-service/2.1/fundi.yaml":
+			_, _ = w.Write([]byte("metadata:\n  templates: " + server.URL + "/bundle.tar.gz//templates\n" +
+				"  values: " + server.URL + "/bundle.tar.gz//values.yml\n"))
+		case "/bundle.tar.gz":
+			_, _ = w.Write(tarball)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	cfg := &config{Registry: server.URL + "/blueprints/index.json"}
+	blueprints := &blueprintResolver{
+		cfg:      cfg,
+		reader:   &fileReader{fs: fs},
+		resolver: &templatesResolver{fs: fs, cfg: cfg, cache: &cache{directory: t.TempDir()}},
+	}
+
+	yamlFile, err := blueprints.resolveBlueprint(context.Background(), "go-service@2.1", "my-svc")
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/blueprints/go-service/2.1/fundi.yaml", yamlFile.path)
+	assert.Equal(t, digest(tarball), yamlFile.templatesVersion)
+
+	data, err := afero.ReadFile(yamlFile.filesystem(fs), filepath.Join(yamlFile.templates(), "main.go.tmpl"))
+	assert.NoError(t, err)
+	assert.Equal(t, "package {{ .package }}\n", string(data))
+}
+
+func TestResolveBuiltinBlueprint(t *testing.T) {
+	for _, name := range []string{"go-cli", "go-service", "go-library"} {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			cfg := &config{OnConflict: string(conflictSkip), Strict: true}
+			blueprints := &blueprintResolver{
+				cfg:      cfg,
+				reader:   &fileReader{fs: fs},
+				resolver: &templatesResolver{fs: fs, cfg: cfg, cache: &cache{directory: "/cache"}},
+			}
+
+			yamlFile, err := blueprints.resolveBlueprint(context.Background(), name, "demo")
+			assert.NoError(t, err)
+
+			yamlFile.setVariables(map[string]string{"name": "demo"})
+
+			_, err = newProjectUseCase(yamlFile.filesystem(fs), cfg, true).
+				ScaffoldProject(context.Background(), yamlFile.toConfigurationFile())
+			assert.NoError(t, err)
+
+			data, err := afero.ReadFile(fs, "demo/go.mod")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(data), "module example.com/demo\n"))
+
+			exists, err := afero.DirExists(fs, mountDirectory())
+			assert.NoError(t, err)
+			assert.False(t, exists, "the blueprint must be read from memory")
+
+			err = afero.Walk(fs, "demo", func(path string, info os.FileInfo, err error) error {
+				if err != nil || filepath.Ext(path) != ".go" {
+					return err
+				}
+
+				source, err := afero.ReadFile(fs, path)
+				if err != nil {
+					return err
+				}
+
+				_, err = format.Source(source)
+				assert.NoError(t, err, path)
+
+				return nil
+			})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	versions := []string{"2.10", "v1.0.0", "2.1", "2.1.1", "10"}
+
+	sort.Slice(versions, func(i, j int) bool { return compareVersions(versions[i], versions[j]) < 0 })
+
+	assert.Equal(t, []string{"v1.0.0", "2.1", "2.1.1", "2.10", "10"}, versions)
+}
@@ -0,0 +1,301 @@
+package app
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+type (
+	// archiveSource is a local or remote .tar.gz or .zip archive, and the path in it to read from.
+	archiveSource struct {
+		location string
+		path     string
+		remote   bool
+	}
+)
+
+const (
+	// archiveCacheDirectory holds the downloaded archives, under the cache directory.
+	archiveCacheDirectory = "archives"
+)
+
+// resolveArchive unpacks the archive source names, and returns the path in it and the sha256 of the archive.
+func (resolver *templatesResolver) resolveArchive(
+	ctx context.Context,
+	yamlFile *yamlFile,
+	source, checksum string,
+) (string, string, error) {
+	archive, err := parseArchiveSource(source)
+	if err != nil || archive == nil {
+		return "", "", err
+	}
+
+	checksum, err = parseChecksum(archive, checksum)
+	if err != nil {
+		return "", "", err
+	}
+
+	data, err := resolver.readArchive(ctx, archive, checksum)
+	if err != nil {
+		return "", "", err
+	}
+
+	version, err := verifyChecksum(data, checksum)
+	if err != nil {
+		return "", "", err
+	}
+
+	mount, err := mountArchive(yamlFile, data, version)
+	if err != nil {
+		return "", "", err
+	}
+
+	return filepath.Join(mount, filepath.FromSlash(archive.path)), version, nil
+}
+
+// parseChecksum returns checksum as the lower case hex sha256 of an archive.
+func parseChecksum(archive *archiveSource, checksum string) (string, error) {
+	if archive.remote && checksum == "" {
+		return "", errors.Errorf("a downloaded archive needs a checksum, set its sha256 under metadata.sha256")
+	}
+
+	checksum = strings.ToLower(strings.TrimPrefix(checksum, "sha256:"))
+	if _, err := hex.DecodeString(checksum); err != nil || (checksum != "" && len(checksum) != sha256.Size*2) {
+		return "", errors.Errorf("invalid checksum %s, use the sha256 of the archive in hex", checksum)
+	}
+
+	return checksum, nil
+}
+
+// mountArchive unpacks the archive data into the sources of yamlFile, and returns where it is.
+func mountArchive(yamlFile *yamlFile, data []byte, version string) (string, error) {
+	if yamlFile.sources == nil {
+		yamlFile.sources = afero.NewMemMapFs()
+	}
+
+	mount := mountDirectory(archiveCacheDirectory, version)
+
+	exists, err := afero.DirExists(yamlFile.sources, mount)
+	if err != nil || exists {
+		return mount, err
+	}
+
+	return mount, unpackArchive(yamlFile.sources, mount, data)
+}
+
+// parseArchiveSource parses an archive source such as https://host/blueprints.tar.gz//go-service.
+func parseArchiveSource(source string) (*archiveSource, error) {
+	remote := isURL(source)
+	if !remote && strings.Contains(source, "://") {
+		return nil, nil
+	}
+
+	scheme, rest := "", source
+	if remote {
+		scheme, rest, _ = strings.Cut(source, "://")
+		scheme += "://"
+	}
+
+	location, subpath, _ := strings.Cut(rest, "//")
+	location = scheme + location
+
+	name := location
+	if remote {
+		u, err := url.Parse(location)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid source %s", source)
+		}
+
+		name = u.Path
+	}
+
+	switch {
+	case !isArchive(name) && remote:
+		return nil, errors.Errorf("invalid source %s, only .tar.gz, .tgz and .zip archives can be downloaded", source)
+	case !isArchive(name):
+		return nil, nil
+	case subpath != "" && !filepath.IsLocal(filepath.FromSlash(subpath)):
+		return nil, errors.Errorf("invalid source %s, path %s is outside the archive", source, subpath)
+	}
+
+	return &archiveSource{location: location, path: subpath, remote: remote}, nil
+}
+
+// isURL reports whether source is an http or https URL.
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// isArchive reports whether name is a .tar.gz or a .zip archive, going by its extension.
+func isArchive(name string) bool {
+	for _, extension := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readArchive returns the contents of the archive, from the file system or, for a remote archive, from the cache.
+func (resolver *templatesResolver) readArchive(
+	ctx context.Context,
+	archive *archiveSource,
+	checksum string,
+) ([]byte, error) {
+	if !archive.remote {
+		return afero.ReadFile(resolver.fs, archive.location)
+	}
+
+	return resolver.downloadArchive(ctx, archive, checksum)
+}
+
+// downloadArchive returns the remote archive from the cache, downloading it when it is not there.
+func (resolver *templatesResolver) downloadArchive(
+	ctx context.Context,
+	archive *archiveSource,
+	checksum string,
+) ([]byte, error) {
+	data, err := resolver.cache.load(archiveCacheDirectory, checksum)
+	if err != nil || data != nil {
+		return data, err
+	}
+
+	if resolver.cfg.Offline {
+		return nil, errors.Errorf("archive %s is not in the cache, %s", archive.location, offlineHint)
+	}
+
+	data, err = download(ctx, archive.location)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := verifyChecksum(data, checksum); err != nil {
+		return nil, err
+	}
+
+	return data, resolver.cache.store(archiveCacheDirectory, checksum, archive.location, data)
+}
+
+// verifyChecksum returns the sha256 of data, and an error when there is a checksum and data does not match it.
+func verifyChecksum(data []byte, checksum string) (string, error) {
+	version := digest(data)
+	if checksum != "" && checksum != version {
+		return "", errors.Errorf("checksum mismatch, expected sha256 %s but got %s", checksum, version)
+	}
+
+	return version, nil
+}
+
+// unpackArchive writes the directories and regular files of a zip or gzipped tar archive into mount.
+func unpackArchive(fs afero.Fs, mount string, data []byte) error {
+	if err := fs.MkdirAll(mount, 0755); err != nil {
+		return err
+	}
+
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return unpackZip(fs, mount, data)
+	}
+
+	return unpackTar(fs, mount, data)
+}
+
+// unpackTar writes the entries of a gzipped tar archive into mount on fs.
+func unpackTar(fs afero.Fs, mount string, data []byte) error {
+	compressed, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "failed to read the archive")
+	}
+
+	reader := tar.NewReader(compressed)
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "failed to read the archive")
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = unpackEntry(fs, mount, header.Name, os.ModeDir|header.FileInfo().Mode().Perm(), nil)
+		case tar.TypeReg:
+			err = unpackEntry(fs, mount, header.Name, header.FileInfo().Mode().Perm(), reader)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unpackZip writes the entries of a zip archive into mount on fs.
+func unpackZip(fs afero.Fs, mount string, data []byte) error {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return errors.Wrap(err, "failed to read the archive")
+	}
+
+	for _, entry := range reader.File {
+		mode := entry.Mode()
+		if !mode.IsDir() && !mode.IsRegular() {
+			continue
+		}
+
+		contents, err := entry.Open()
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s in the archive", entry.Name)
+		}
+
+		err = unpackEntry(fs, mount, entry.Name, mode, contents)
+		_ = contents.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unpackEntry writes the directory or file name of an archive into mount on fs, refusing names that lead out of it.
+func unpackEntry(fs afero.Fs, mount, name string, mode os.FileMode, contents io.Reader) error {
+	relative := filepath.FromSlash(path.Clean(strings.TrimPrefix(name, "/")))
+	if !filepath.IsLocal(relative) {
+		return errors.Errorf("archive entry %s is outside the archive", name)
+	}
+
+	target := filepath.Join(mount, relative)
+
+	if mode.IsDir() {
+		return fs.MkdirAll(target, mode.Perm()|0700)
+	}
+
+	if err := fs.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(contents)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s in the archive", name)
+	}
+
+	return afero.WriteFile(fs, target, data, mode.Perm())
+}
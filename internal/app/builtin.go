@@ -0,0 +1,110 @@
+package app
+
+import (
+	"embed"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// builtinBlueprints are the blueprints built into fundi, a directory each.
+//
+//go:embed all:blueprints
+var builtinBlueprints embed.FS
+
+const (
+	// builtinBlueprintsDirectory holds the built-in blueprints, in builtinBlueprints.
+	builtinBlueprintsDirectory = "blueprints"
+
+	// builtinMountDirectory holds the built-in blueprints read into memory, under the mount directory.
+	builtinMountDirectory = "builtin"
+
+	// builtinConfigFile is the config file of a built-in blueprint.
+	builtinConfigFile = ".fundi.yaml"
+)
+
+// resolveBuiltinBlueprint returns the config file of the blueprint built into fundi named name.
+func (br *blueprintResolver) resolveBuiltinBlueprint(name, output string) (*yamlFile, error) {
+	blueprints := afero.FromIOFS{FS: builtinBlueprints}
+
+	names, err := builtinBlueprintNames(blueprints)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(names, name) {
+		return nil, errors.Errorf("blueprint %s is not built into fundi, use one of %s, or set a registry with "+
+			"--registry or FUNDI_REGISTRY", name, strings.Join(names, ", "))
+	}
+
+	sources := afero.NewMemMapFs()
+	mount := mountDirectory(builtinMountDirectory, name)
+
+	if err := copyBuiltinBlueprint(blueprints, path.Join(builtinBlueprintsDirectory, name), sources, mount); err != nil {
+		return nil, errors.Wrapf(err, "failed to read blueprint %s", name)
+	}
+
+	configFile := filepath.Join(mount, builtinConfigFile)
+
+	yamlFile, err := newFileReader(sources).readYAMLFile(configFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read blueprint %s", name)
+	}
+
+	yamlFile.sources = sources
+
+	metadata := yamlFile.Metadata
+	metadata.Templates = relativeTo(configFile, metadata.Templates)
+	metadata.Values = relativeTo(configFile, metadata.Values)
+
+	if output != "" {
+		metadata.Output = output
+	}
+
+	return yamlFile, nil
+}
+
+// builtinBlueprintNames returns the names of the blueprints built into fundi, in order.
+func builtinBlueprintNames(blueprints afero.Fs) ([]string, error) {
+	entries, err := afero.ReadDir(blueprints, builtinBlueprintsDirectory)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the built-in blueprints")
+	}
+
+	names := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// copyBuiltinBlueprint copies the blueprint in directory of blueprints into mount on fs.
+func copyBuiltinBlueprint(blueprints afero.Fs, directory string, fs afero.Fs, mount string) error {
+	return afero.Walk(blueprints, directory, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(mount, filepath.FromSlash(strings.TrimPrefix(name, directory)))
+
+		if info.IsDir() {
+			return fs.MkdirAll(target, 0755)
+		}
+
+		data, err := afero.ReadFile(blueprints, name)
+		if err != nil {
+			return err
+		}
+
+		return afero.WriteFile(fs, target, data, 0644)
+	})
+}
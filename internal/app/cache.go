@@ -0,0 +1,380 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// cache is the directory fundi keeps what it fetched in.
+	cache struct {
+		directory string
+	}
+
+	// cacheEntry is a git checkout or a download in the cache, versioned by its commit or sha256.
+	cacheEntry struct {
+		kind    string
+		source  string
+		version string
+		path    string
+		size    int64
+		used    time.Time
+	}
+)
+
+const (
+	cacheKindGit      = "git"
+	cacheKindArchive  = "archive"
+	cacheKindConfig   = "config"
+	cacheKindRegistry = "registry"
+
+	// configCacheDirectory holds the downloaded config files, under the cache directory.
+	configCacheDirectory = "configs"
+
+	// registryCacheDirectory holds the downloaded blueprint registries, under the cache directory.
+	registryCacheDirectory = "registries"
+
+	// cacheSourceFile names where a downloaded archive or config file came from, in its entry.
+	cacheSourceFile = "source"
+
+	// cacheDataFile holds a downloaded archive or config file, in its entry.
+	cacheDataFile = "data"
+
+	// offlineHint tells what to do when the cache is missing something in offline mode.
+	offlineHint = "run without --offline to fetch it"
+)
+
+// path returns the cache directory, finding it under the user's cache directory when it is not set.
+func (c *cache) path() (string, error) {
+	if c.directory != "" {
+		return c.directory, nil
+	}
+
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find the cache directory, set FUNDI_CACHE_DIR")
+	}
+
+	c.directory = filepath.Join(userCache, "fundi")
+
+	return c.directory, nil
+}
+
+// load returns the data kept under key in the cache directory kind, or nil when it is not there.
+func (c *cache) load(kind, key string) ([]byte, error) {
+	directory, err := c.path()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := filepath.Join(directory, kind, key)
+
+	data, err := os.ReadFile(filepath.Join(entry, cacheDataFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, touch(entry)
+}
+
+// store keeps data downloaded from source under key in the cache directory kind.
+func (c *cache) store(kind, key, source string, data []byte) error {
+	directory, err := c.path()
+	if err != nil {
+		return err
+	}
+
+	parent := filepath.Join(directory, kind)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return errors.Wrap(err, "failed to create the cache directory")
+	}
+
+	staging, err := os.MkdirTemp(parent, key+"-")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.RemoveAll(staging) }()
+
+	if err := os.WriteFile(filepath.Join(staging, cacheSourceFile), []byte(source), 0600); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(staging, cacheDataFile), data, 0600); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Join(parent, key)); err != nil {
+		return err
+	}
+
+	return os.Rename(staging, filepath.Join(parent, key))
+}
+
+// entries returns what the cache holds, sorted by kind, source and version.
+func (c *cache) entries(ctx context.Context) ([]*cacheEntry, error) {
+	directory, err := c.path()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*cacheEntry, 0)
+
+	repositories, err := c.gitEntries(ctx, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	entries = append(entries, repositories...)
+
+	for kind, kindDirectory := range map[string]string{
+		cacheKindArchive:  archiveCacheDirectory,
+		cacheKindConfig:   configCacheDirectory,
+		cacheKindRegistry: registryCacheDirectory,
+	} {
+		downloads, err := downloadEntries(kind, filepath.Join(directory, kindDirectory))
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, downloads...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].kind != entries[j].kind {
+			return entries[i].kind < entries[j].kind
+		}
+
+		if entries[i].source != entries[j].source {
+			return entries[i].source < entries[j].source
+		}
+
+		return entries[i].version < entries[j].version
+	})
+
+	return entries, nil
+}
+
+// gitEntries returns the checkouts of the git repositories in the cache directory.
+func (c *cache) gitEntries(ctx context.Context, directory string) ([]*cacheEntry, error) {
+	repositories, err := readCacheDirectory(filepath.Join(directory, gitCacheDirectory))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*cacheEntry, 0)
+
+	for _, repository := range repositories {
+		mirror := filepath.Join(repository, gitMirrorDirectory)
+
+		source, err := runGit(ctx, mirror, "config", "--get", "remote.origin.url")
+		if err != nil {
+			source = filepath.Base(repository)
+		}
+
+		checkouts, err := readCacheDirectory(repository)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, checkout := range checkouts {
+			if checkout == mirror {
+				continue
+			}
+
+			entry, err := newCacheEntry(cacheKindGit, source, filepath.Base(checkout), checkout)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// downloadEntries returns the downloads of kind in directory.
+func downloadEntries(kind, directory string) ([]*cacheEntry, error) {
+	paths, err := readCacheDirectory(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*cacheEntry, 0, len(paths))
+
+	for _, path := range paths {
+		source, err := os.ReadFile(filepath.Join(path, cacheSourceFile))
+		if err != nil {
+			// an entry another run is still writing, or one it left behind, has no source yet.
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(path, cacheDataFile))
+		if err != nil {
+			continue
+		}
+
+		entry, err := newCacheEntry(kind, string(source), digest(data), path)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// prune removes the entries of the cache that were last used before cutoff, and returns them.
+func (c *cache) prune(ctx context.Context, cutoff time.Time) ([]*cacheEntry, error) {
+	entries, err := c.entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]*cacheEntry, 0)
+
+	for _, entry := range entries {
+		if !entry.used.Before(cutoff) {
+			continue
+		}
+
+		if err := os.RemoveAll(entry.path); err != nil {
+			return removed, errors.Wrapf(err, "failed to remove %s from the cache", entry.path)
+		}
+
+		removed = append(removed, entry)
+	}
+
+	repositories, err := readCacheDirectory(filepath.Join(c.directory, gitCacheDirectory))
+	if err != nil {
+		return removed, err
+	}
+
+	for _, repository := range repositories {
+		checkouts, err := readCacheDirectory(repository)
+		if err != nil {
+			return removed, err
+		}
+
+		if len(checkouts) <= 1 {
+			if err := os.RemoveAll(repository); err != nil {
+				return removed, errors.Wrapf(err, "failed to remove %s from the cache", repository)
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// clear removes everything in the cache.
+func (c *cache) clear() error {
+	directory, err := c.path()
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(directory); err != nil {
+		return errors.Wrapf(err, "failed to clear the cache %s", directory)
+	}
+
+	return nil
+}
+
+// printEntries prints entries as a table to w.
+func printEntries(w io.Writer, entries []*cacheEntry) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if _, err := fmt.Fprintln(table, "KIND\tSOURCE\tVERSION\tSIZE\tLAST USED"); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		_, err := fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%s\n", entry.kind, entry.source, entry.version, entry.size,
+			entry.used.Format(time.DateTime))
+		if err != nil {
+			return err
+		}
+	}
+
+	return table.Flush()
+}
+
+// newCacheEntry returns the entry of the cache at path, with its size and the time it was last used.
+func newCacheEntry(kind, source, version, path string) (*cacheEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &cacheEntry{kind: kind, source: source, version: version, path: path, used: info.ModTime()}
+
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		entry.size += info.Size()
+
+		return nil
+	})
+
+	return entry, err
+}
+
+// readCacheDirectory returns the paths of the directories in directory, or none when it doesn't exist.
+func readCacheDirectory(directory string) ([]string, error) {
+	entries, err := os.ReadDir(directory)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			paths = append(paths, filepath.Join(directory, entry.Name()))
+		}
+	}
+
+	return paths, nil
+}
+
+// touch marks the entry of the cache at path as used now.
+func touch(path string) error {
+	now := time.Now()
+
+	return os.Chtimes(path, now, now)
+}
+
+// cacheKey returns the key that source is kept under in the cache.
+func cacheKey(source string) string {
+	sum := sha256.Sum256([]byte(source))
+
+	return hex.EncodeToString(sum[:8])
+}
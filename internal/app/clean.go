@@ -0,0 +1,183 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+const (
+	actionRemove = "remove"
+	actionKeep   = "keep"
+)
+
+// cleanProject removes the files and directories fundi generated into output, as its lock file lists them.
+func cleanProject(fs afero.Fs, output string, force bool) error {
+	lock, err := readLockFile(fs, output)
+	if err != nil {
+		return err
+	}
+
+	kept := make([]*lockedFile, 0)
+
+	for _, file := range lock.Files {
+		removed, err := cleanFile(fs, output, file, force)
+		if err != nil {
+			return errors.Wrapf(err, "failed to remove file %s", filepath.Join(output, file.Path))
+		}
+
+		if !removed {
+			kept = append(kept, file)
+		}
+	}
+
+	directories, err := cleanDirectories(fs, output, lock.Directories)
+	if err != nil {
+		return err
+	}
+
+	if len(kept) > 0 || len(directories) > 0 {
+		lock.Files = kept
+		lock.Directories = directories
+		sort.Strings(lock.Directories)
+
+		if err := removeEmptyRecords(fs, output); err != nil {
+			return err
+		}
+
+		return lock.write(fs, output)
+	}
+
+	if err := fs.RemoveAll(filepath.Join(output, stateDirectory)); err != nil {
+		return errors.Wrap(err, "failed to remove the records of the generated files")
+	}
+
+	if err := fs.Remove(filepath.Join(output, lockFileName)); err != nil {
+		return errors.Wrap(err, "failed to remove the lock file")
+	}
+
+	return nil
+}
+
+// cleanFile removes a generated file along with its record, and reports whether it is gone.
+func cleanFile(fs afero.Fs, output string, file *lockedFile, force bool) (bool, error) {
+	if file.Symlink != "" {
+		return cleanSymlink(fs, output, file, force)
+	}
+
+	path := filepath.Join(output, file.Path)
+
+	data, err := afero.ReadFile(fs, path)
+	if os.IsNotExist(err) {
+		return true, removeRecord(fs, output, file.Path)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if digest(data) != file.SHA256 && !force {
+		fmt.Printf("%-9s %s (modified since it was generated)\n", actionKeep, path)
+
+		return false, nil
+	}
+
+	if err := fs.Remove(path); err != nil {
+		return false, err
+	}
+
+	fmt.Printf("%-9s %s\n", actionRemove, path)
+
+	return true, removeRecord(fs, output, file.Path)
+}
+
+// cleanDirectories removes the directories under output that are empty, and returns those that are kept.
+func cleanDirectories(fs afero.Fs, output string, directories []string) ([]string, error) {
+	kept := make([]string, 0)
+
+	sortDeepestFirst(directories)
+
+	for _, directory := range directories {
+		removed, err := removeEmptyDirectory(fs, filepath.Join(output, directory), false)
+		if err != nil {
+			return nil, err
+		}
+
+		if !removed {
+			kept = append(kept, directory)
+		}
+	}
+
+	return kept, nil
+}
+
+// removeEmptyRecords removes the directories of the state directory that no record is left in.
+func removeEmptyRecords(fs afero.Fs, output string) error {
+	directories := make([]string, 0)
+
+	err := afero.Walk(fs, filepath.Join(output, stateDirectory),
+		func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				directories = append(directories, path)
+			}
+
+			return nil
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the records of the generated files")
+	}
+
+	sortDeepestFirst(directories)
+
+	for _, directory := range directories {
+		if _, err := removeEmptyDirectory(fs, directory, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortDeepestFirst sorts directories to put each of them before its parent.
+func sortDeepestFirst(directories []string) {
+	sort.Slice(directories, func(i, j int) bool { return len(directories[i]) > len(directories[j]) })
+}
+
+// removeEmptyDirectory removes the directory at path when it is empty, and reports whether it is gone.
+func removeEmptyDirectory(fs afero.Fs, path string, quiet bool) (bool, error) {
+	entries, err := afero.ReadDir(fs, path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read directory %s", path)
+	}
+
+	if len(entries) > 0 {
+		return false, nil
+	}
+
+	if err := fs.Remove(path); err != nil {
+		return false, errors.Wrapf(err, "failed to remove directory %s", path)
+	}
+
+	if !quiet {
+		fmt.Printf("%-9s %s%c\n", actionRemove, path, os.PathSeparator)
+	}
+
+	return true, nil
+}
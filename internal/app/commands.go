@@ -1,10 +1,15 @@
 package app
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
 	"github.com/kasulani/go-fundi/internal/generate"
@@ -27,6 +32,22 @@ type (
 	subCommands []SubCommand
 
 	generateProjectCommand Command
+
+	newProjectCommand Command
+
+	diffProjectCommand Command
+
+	updateProjectCommand Command
+
+	cleanProjectCommand Command
+
+	cacheCommand Command
+
+	// generateOptions are the flags of the generate and new commands that are not part of the config.
+	generateOptions struct {
+		variables map[string]string
+		dryRun    bool
+	}
 )
 
 func newRootCommand() *rootCommand {
@@ -42,10 +63,17 @@ func newRootCommand() *rootCommand {
 
 func newGenerateProjectCommand(
 	ctx context.Context,
+	cfg *config,
+	fs afero.Fs,
 	reader *fileReader,
-	useCase *generate.ProjectUseCase,
+	resolver *templatesResolver,
+	blueprints *blueprintResolver,
 ) *generateProjectCommand {
-	var filePath string
+	var (
+		filePath  string
+		blueprint string
+		options   generateOptions
+	)
 
 	cmd := &generateProjectCommand{
 		&cobra.Command{
@@ -53,13 +81,67 @@ func newGenerateProjectCommand(
 			Short: "generate your project directory structure and files",
 			Long:  `use this subcommand to generate your project directory structure and files.`,
 			Run: func(cmd *cobra.Command, args []string) {
-				yamlFile, err := reader.readYAMLFile(filePath)
+				err := runGenerate(ctx, cfg, fs, &options, func() (*yamlFile, error) {
+					if blueprint != "" {
+						return blueprints.resolveBlueprint(ctx, blueprint, "")
+					}
+
+					return readConfigFile(ctx, reader, resolver, filePath)
+				})
 				if err != nil {
 					fmt.Println(err)
 					os.Exit(1)
 				}
 
-				if err := useCase.ScaffoldProject(ctx, yamlFile.toConfigurationFile()); err != nil {
+				os.Exit(0)
+			},
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&filePath,
+		"config-file",
+		"f",
+		"./.fundi.yaml",
+		"path to your config file",
+	)
+	cmd.Flags().StringVar(
+		&blueprint,
+		"blueprint",
+		"",
+		"generate from a blueprint built into fundi, such as go-cli, or in the registry, instead of a config file",
+	)
+	addGenerateFlags(cmd.Command, cfg, &options)
+	cmd.MarkFlagsMutuallyExclusive("config-file", "blueprint")
+
+	return cmd
+}
+
+func (cmd *generateProjectCommand) AddTo(root *rootCommand) {
+	root.AddCommand(cmd.Command)
+}
+
+func newNewProjectCommand(
+	ctx context.Context,
+	cfg *config,
+	fs afero.Fs,
+	blueprints *blueprintResolver,
+) *newProjectCommand {
+	var options generateOptions
+
+	cmd := &newProjectCommand{
+		&cobra.Command{
+			Use:   "new <blueprint>[@version] <directory>",
+			Short: "generate a new project from a blueprint in a registry, or one built into fundi",
+			Long: `use this subcommand to generate a new project into a directory from a named, versioned blueprint in a
+registry, such as go-service@2.1. Without a version, the latest version of the blueprint is used. Without a registry,
+the blueprint is one of those built into fundi: go-cli, go-service or go-library.`,
+			Args: cobra.ExactArgs(2),
+			Run: func(cmd *cobra.Command, args []string) {
+				err := runGenerate(ctx, cfg, fs, &options, func() (*yamlFile, error) {
+					return blueprints.resolveBlueprint(ctx, args[0], args[1])
+				})
+				if err != nil {
 					fmt.Println(err)
 					os.Exit(1)
 				}
@@ -69,6 +151,248 @@ func newGenerateProjectCommand(
 		},
 	}
 
+	addGenerateFlags(cmd.Command, cfg, &options)
+
+	return cmd
+}
+
+func (cmd *newProjectCommand) AddTo(root *rootCommand) {
+	root.AddCommand(cmd.Command)
+}
+
+// addGenerateFlags adds the flags that the generate and new commands share to cmd.
+func addGenerateFlags(cmd *cobra.Command, cfg *config, options *generateOptions) {
+	cmd.Flags().StringVar(
+		&cfg.Registry,
+		"registry",
+		cfg.Registry,
+		"path or URL of the registry to find the blueprint in",
+	)
+	cmd.Flags().StringToStringVar(
+		&options.variables,
+		"var",
+		nil,
+		"set a variable over the one the config file or blueprint sets, such as --var name=my-cli",
+	)
+	cmd.Flags().BoolVar(
+		&options.dryRun,
+		"dry-run",
+		false,
+		"print the directories and files that would be generated without writing them",
+	)
+	cmd.Flags().StringVar(
+		&cfg.OnConflict,
+		"on-conflict",
+		cfg.OnConflict,
+		"what to do with existing files that differ from the generated ones: skip, overwrite, fail, backup or prompt",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.Strict,
+		"strict",
+		cfg.Strict,
+		"fail when a template or the values file refers to a missing value",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.Offline,
+		"offline",
+		cfg.Offline,
+		"read remote registries, config files, templates and values from the cache only, and fail when it is missing any",
+	)
+}
+
+// runGenerate generates the project of the config file that resolve returns, or previews it on a dry run.
+func runGenerate(
+	ctx context.Context,
+	cfg *config,
+	fs afero.Fs,
+	options *generateOptions,
+	resolve func() (*yamlFile, error),
+) error {
+	if _, err := parseConflictPolicy(cfg.OnConflict); err != nil {
+		return err
+	}
+
+	yamlFile, err := resolve()
+	if err != nil {
+		return err
+	}
+
+	yamlFile.setVariables(options.variables)
+
+	if options.dryRun {
+		return previewProject(ctx, cfg, yamlFile.filesystem(fs), yamlFile)
+	}
+
+	project, err := generateProject(ctx, cfg, yamlFile.filesystem(fs), yamlFile)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range project.GetSkippedPaths() {
+		fmt.Printf("skipped %s\n", filepath.Join(yamlFile.Metadata.Output, path))
+	}
+
+	return nil
+}
+
+// readConfigFile reads the config file at path, which can be a URL, and fetches its templates and values.
+func readConfigFile(
+	ctx context.Context,
+	reader *fileReader,
+	resolver *templatesResolver,
+	path string,
+) (*yamlFile, error) {
+	yamlFile, err := loadConfigFile(ctx, reader, resolver, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolver.resolveSources(ctx, yamlFile); err != nil {
+		return nil, err
+	}
+
+	return yamlFile, nil
+}
+
+// loadConfigFile reads the config file at path, downloading it when path is a URL.
+func loadConfigFile(
+	ctx context.Context,
+	reader *fileReader,
+	resolver *templatesResolver,
+	path string,
+) (*yamlFile, error) {
+	if isURL(path) {
+		return resolver.downloadConfigFile(ctx, path)
+	}
+
+	return reader.readYAMLFile(path)
+}
+
+// generateProject generates the project and its lock file into a staging workspace, and commits it.
+func generateProject(ctx context.Context, cfg *config, fs afero.Fs, yamlFile *yamlFile) (*generate.Project, error) {
+	ws, err := newStagingWorkspace(fs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create a staging directory")
+	}
+
+	defer func() { _ = ws.discard() }()
+
+	// the staging directory only holds paths under it, and a relative output directory may lead out of it.
+	output, err := filepath.Abs(yamlFile.Metadata.Output)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find output directory %s", yamlFile.Metadata.Output)
+	}
+
+	staged, metadata := *yamlFile, *yamlFile.Metadata
+	metadata.Output = output
+	staged.Metadata = &metadata
+
+	project, err := newProjectUseCase(ws, cfg, false).ScaffoldProject(ctx, staged.toConfigurationFile())
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := ws.changes(output)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the changes to the project")
+	}
+
+	if err := writeLockFile(ws, &staged, project, createdDirectories(output, changes)); err != nil {
+		return nil, err
+	}
+
+	if err := ws.commit(output); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// previewProject prints what generating the project would change.
+func previewProject(ctx context.Context, cfg *config, fs afero.Fs, yamlFile *yamlFile) error {
+	dryRun := *cfg
+	dryRun.DryRun = true
+
+	ws, project, err := stageProject(ctx, &dryRun, fs, yamlFile, false)
+	if err != nil {
+		return err
+	}
+
+	changes, err := ws.changes(yamlFile.Metadata.Output)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the changes to the project")
+	}
+
+	for _, change := range changes {
+		fmt.Printf("%-9s %s\n", change.action, change)
+	}
+
+	for _, path := range project.GetSkippedPaths() {
+		fmt.Printf("%-9s %s\n", actionSkip, filepath.Join(yamlFile.Metadata.Output, path))
+	}
+
+	return nil
+}
+
+// stageProject generates the project into a workspace on top of fs, overwriting every file there when overwrite is set.
+func stageProject(
+	ctx context.Context,
+	cfg *config,
+	fs afero.Fs,
+	yamlFile *yamlFile,
+	overwrite bool,
+) (*workspace, *generate.Project, error) {
+	ws := newWorkspace(fs)
+	useCase := generate.NewProjectUseCase(
+		&directoryCreator{fs: ws, quiet: true},
+		&filesCreator{fs: ws, cfg: cfg, quiet: true, overwrite: overwrite},
+	)
+
+	project, err := useCase.ScaffoldProject(ctx, yamlFile.toConfigurationFile())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ws, project, nil
+}
+
+func newDiffProjectCommand(
+	ctx context.Context,
+	cfg *config,
+	fs afero.Fs,
+	reader *fileReader,
+	resolver *templatesResolver,
+) *diffProjectCommand {
+	var filePath string
+
+	cmd := &diffProjectCommand{
+		&cobra.Command{
+			Use:   "diff",
+			Short: "show how the generated project differs from the files on disk",
+			Long: `use this subcommand to print a unified diff between the project your config file generates and the files
+already in its output directory. It exits with code 1 when they differ, and 2 when the diff can't be made.`,
+			Run: func(cmd *cobra.Command, args []string) {
+				yamlFile, err := readConfigFile(ctx, reader, resolver, filePath)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(2)
+				}
+
+				differs, err := diffProject(ctx, cfg, yamlFile.filesystem(fs), yamlFile)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(2)
+				}
+
+				if differs {
+					os.Exit(1)
+				}
+
+				os.Exit(0)
+			},
+		},
+	}
+
 	cmd.PersistentFlags().StringVarP(
 		&filePath,
 		"config-file",
@@ -76,10 +400,360 @@ func newGenerateProjectCommand(
 		"./.fundi.yaml",
 		"path to your config file",
 	)
+	cmd.Flags().BoolVar(
+		&cfg.Strict,
+		"strict",
+		cfg.Strict,
+		"fail when a template or the values file refers to a missing value",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.Offline,
+		"offline",
+		cfg.Offline,
+		"read remote config files, templates and values from the cache only, and fail when it is missing any",
+	)
 
 	return cmd
 }
 
-func (cmd *generateProjectCommand) AddTo(root *rootCommand) {
+func (cmd *diffProjectCommand) AddTo(root *rootCommand) {
+	root.AddCommand(cmd.Command)
+}
+
+// diffProject prints a unified diff between the generated project and the files on disk.
+func diffProject(ctx context.Context, cfg *config, fs afero.Fs, yamlFile *yamlFile) (bool, error) {
+	ws, _, err := stageProject(ctx, cfg, fs, yamlFile, true)
+	if err != nil {
+		return false, err
+	}
+
+	changes, err := ws.changes(yamlFile.Metadata.Output)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to list the changes to the project")
+	}
+
+	differs := false
+
+	for _, change := range changes {
+		diff, err := ws.diff(change)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to diff %s", change)
+		}
+
+		if diff != "" {
+			differs = true
+			fmt.Print(diff)
+		}
+	}
+
+	return differs, nil
+}
+
+func newUpdateProjectCommand(
+	ctx context.Context,
+	cfg *config,
+	fs afero.Fs,
+	reader *fileReader,
+	resolver *templatesResolver,
+) *updateProjectCommand {
+	var filePath string
+
+	cmd := &updateProjectCommand{
+		&cobra.Command{
+			Use:   "update",
+			Short: "merge changes to your templates into a project generated before",
+			Long: `use this subcommand to regenerate a project and three-way merge the new output into files you have edited
+since fundi last generated them. Lines that both you and the templates changed are left between conflict markers,
+and the command exits with code 1 until you resolve them.`,
+			Run: func(cmd *cobra.Command, args []string) {
+				yamlFile, err := readConfigFile(ctx, reader, resolver, filePath)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+
+				conflicts, err := updateProject(ctx, cfg, yamlFile.filesystem(fs), yamlFile)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+
+				if conflicts {
+					os.Exit(1)
+				}
+
+				os.Exit(0)
+			},
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&filePath,
+		"config-file",
+		"f",
+		"./.fundi.yaml",
+		"path to your config file",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.Strict,
+		"strict",
+		cfg.Strict,
+		"fail when a template or the values file refers to a missing value",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.Offline,
+		"offline",
+		cfg.Offline,
+		"read remote config files, templates and values from the cache only, and fail when it is missing any",
+	)
+
+	return cmd
+}
+
+func (cmd *updateProjectCommand) AddTo(root *rootCommand) {
+	root.AddCommand(cmd.Command)
+}
+
+// updateProject merges the regenerated project into the files on disk, and reports whether conflicts are left.
+func updateProject(ctx context.Context, cfg *config, fs afero.Fs, yamlFile *yamlFile) (bool, error) {
+	ws, project, err := stageProject(ctx, cfg, fs, yamlFile, true)
+	if err != nil {
+		return false, err
+	}
+
+	changes, err := ws.changes(yamlFile.Metadata.Output)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to list the changes to the project")
+	}
+
+	conflicts := false
+
+	for _, change := range changes {
+		action, err := updateChange(fs, ws, yamlFile.Metadata.Output, change)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to update %s", change)
+		}
+
+		conflicts = conflicts || action == actionConflict
+		fmt.Printf("%-9s %s\n", action, change)
+	}
+
+	return conflicts, writeLockFile(fs, yamlFile, project, createdDirectories(yamlFile.Metadata.Output, changes))
+}
+
+// updateChange applies a change from the workspace to the file system, and returns what it did.
+func updateChange(fs afero.Fs, ws *workspace, output string, c *change) (string, error) {
+	if c.link != "" {
+		return updateSymlink(fs, c)
+	}
+
+	info, err := ws.layer.Stat(c.path)
+	if err != nil {
+		return "", err
+	}
+
+	if c.isDir {
+		return c.action, fs.MkdirAll(c.path, info.Mode().Perm())
+	}
+
+	name, err := filepath.Rel(output, c.path)
+	if err != nil {
+		return "", err
+	}
+
+	theirs, err := afero.ReadFile(ws.layer, c.path)
+	if err != nil {
+		return "", err
+	}
+
+	action, data := c.action, theirs
+
+	if c.action == actionOverwrite {
+		base, err := readRecord(fs, output, name)
+		if err != nil {
+			return "", err
+		}
+
+		if action, data, err = mergeChange(fs, c.path, base, theirs); err != nil {
+			return "", err
+		}
+	}
+
+	return action, writeChange(fs, output, name, action, data, theirs, info.Mode().Perm())
+}
+
+// mergeChange merges theirs into the edited file at path, and returns the action and the merged file.
+func mergeChange(fs afero.Fs, path string, base, theirs []byte) (string, []byte, error) {
+	ours, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if hasConflictMarkers(ours) {
+		return actionConflict, ours, nil
+	}
+
+	merged, conflicts := merge(base, ours, theirs)
+
+	switch {
+	case conflicts > 0:
+		return actionConflict, merged, nil
+	case !bytes.Equal(merged, theirs):
+		return actionMerge, merged, nil
+	}
+
+	return actionOverwrite, merged, nil
+}
+
+// writeChange writes data to the file at name under output, and records theirs as its generated version.
+func writeChange(fs afero.Fs, output, name, action string, data, theirs []byte, perm os.FileMode) error {
+	if action != actionUnchanged {
+		if err := afero.WriteFile(fs, filepath.Join(output, name), data, perm); err != nil {
+			return err
+		}
+	}
+
+	if action == actionConflict {
+		return nil
+	}
+
+	return writeRecord(fs, output, name, theirs)
+}
+
+func newCleanProjectCommand(fs afero.Fs, reader *fileReader) *cleanProjectCommand {
+	var (
+		filePath string
+		force    bool
+	)
+
+	cmd := &cleanProjectCommand{
+		&cobra.Command{
+			Use:   "clean [directory]",
+			Short: "remove the directories and files fundi generated",
+			Long: `use this subcommand to remove the directories and files a previous run generated, as the lock file in the
+output directory lists them. Pass the output directory, or the config file the project was generated from. Files you
+edited since they were generated are kept, unless you pass --force.`,
+			Args: cobra.MaximumNArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				output, err := cleanOutput(reader, filePath, args)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+
+				if err := cleanProject(fs, output, force); err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+
+				os.Exit(0)
+			},
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(
+		&filePath,
+		"config-file",
+		"f",
+		"./.fundi.yaml",
+		"path to your config file",
+	)
+	cmd.Flags().BoolVar(
+		&force,
+		"force",
+		false,
+		"remove generated files even when they were edited since",
+	)
+
+	return cmd
+}
+
+// cleanOutput returns the output directory to clean, the one in args or else that of the config file at filePath.
+func cleanOutput(reader *fileReader, filePath string, args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	yamlFile, err := reader.readYAMLFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	return yamlFile.Metadata.Output, nil
+}
+
+func (cmd *cleanProjectCommand) AddTo(root *rootCommand) {
+	root.AddCommand(cmd.Command)
+}
+
+func newCacheCommand(ctx context.Context, cache *cache) *cacheCommand {
+	var olderThan time.Duration
+
+	cmd := &cacheCommand{
+		&cobra.Command{
+			Use:   "cache",
+			Short: "manage the cache of remote templates, values and config files",
+			Long: `use this subcommand to list, prune or clear the git repositories, archives, config files and registries
+fundi fetched. They are kept in $XDG_CACHE_HOME/fundi, or in FUNDI_CACHE_DIR when it is set.`,
+		},
+	}
+
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "list what the cache holds",
+		Run: func(cmd *cobra.Command, args []string) {
+			entries, err := cache.entries(ctx)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			if err := printEntries(os.Stdout, entries); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	pruneCommand := &cobra.Command{
+		Use:   "prune",
+		Short: "remove what was not used recently from the cache",
+		Run: func(cmd *cobra.Command, args []string) {
+			removed, err := cache.prune(ctx, time.Now().Add(-olderThan))
+			for _, entry := range removed {
+				fmt.Printf("%-9s %s %s %s\n", actionRemove, entry.kind, entry.source, entry.version)
+			}
+
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	pruneCommand.Flags().DurationVar(
+		&olderThan,
+		"older-than",
+		30*24*time.Hour,
+		"remove what was last used longer ago than this",
+	)
+
+	clearCommand := &cobra.Command{
+		Use:   "clear",
+		Short: "remove everything from the cache",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cache.clear(); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.AddCommand(listCommand, pruneCommand, clearCommand)
+
+	return cmd
+}
+
+func (cmd *cacheCommand) AddTo(root *rootCommand) {
 	root.AddCommand(cmd.Command)
 }
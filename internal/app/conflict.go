@@ -0,0 +1,153 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/afero"
+	"golang.org/x/term"
+
+	"github.com/kasulani/go-fundi/internal/generate"
+)
+
+type (
+	// conflictPolicy decides what happens to a file that already exists and differs from the generated one.
+	conflictPolicy string
+
+	// conflictPlanner is a file system that keeps the conflicts of a dry run, the way a workspace does.
+	conflictPlanner interface {
+		conflict(path string, policy conflictPolicy)
+	}
+)
+
+const (
+	conflictSkip      conflictPolicy = "skip"
+	conflictOverwrite conflictPolicy = "overwrite"
+	conflictFail      conflictPolicy = "fail"
+	conflictBackup    conflictPolicy = "backup"
+	conflictPrompt    conflictPolicy = "prompt"
+
+	backupExtension = ".bak"
+)
+
+var (
+	conflictPolicies = []conflictPolicy{conflictSkip, conflictOverwrite, conflictFail, conflictBackup, conflictPrompt}
+
+	// isTerminal reports whether the file descriptor is a terminal.
+	isTerminal = term.IsTerminal
+)
+
+// parseConflictPolicy returns the policy named by value, or by the boolean of a file's overwrite setting.
+func parseConflictPolicy(value string) (conflictPolicy, error) {
+	switch value {
+	case "true":
+		return conflictOverwrite, nil
+	case "false":
+		return conflictSkip, nil
+	}
+
+	for _, policy := range conflictPolicies {
+		if conflictPolicy(value) == policy {
+			return policy, nil
+		}
+	}
+
+	names := make([]string, len(conflictPolicies))
+	for i, policy := range conflictPolicies {
+		names[i] = string(policy)
+	}
+
+	return "", errors.Errorf("unknown conflict policy %q, use one of %s", value, strings.Join(names, ", "))
+}
+
+// resolveConflict reports whether data may be written to path, by the policy when a different file is there.
+func (fc *filesCreator) resolveConflict(path string, data []byte, policy conflictPolicy) (bool, error) {
+	exists, err := afero.Exists(fc.fs, path)
+	if err != nil || !exists {
+		return true, err
+	}
+
+	current, err := afero.ReadFile(fc.fs, path)
+	if err != nil {
+		return false, err
+	}
+
+	if bytes.Equal(current, data) {
+		return true, nil
+	}
+
+	return fc.applyPolicy(path, current, policy)
+}
+
+// applyPolicy reports whether the file at path, which holds current, may be overwritten under policy.
+func (fc *filesCreator) applyPolicy(path string, current []byte, policy conflictPolicy) (bool, error) {
+	if fc.cfg.DryRun && policy != conflictOverwrite {
+		if err := fc.planConflict(path, policy); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	switch policy {
+	case conflictOverwrite:
+		return true, nil
+	case conflictFail:
+		return false, errors.Errorf("file %s already exists", path)
+	case conflictBackup:
+		if err := afero.WriteFile(fc.fs, path+backupExtension, current, 0644); err != nil {
+			return false, errors.Wrapf(err, "failed to back up file %s", path)
+		}
+
+		return true, nil
+	case conflictPrompt:
+		overwrite, err := askToOverwrite(path)
+		if err != nil || overwrite {
+			return overwrite, err
+		}
+	}
+
+	fmt.Printf("%-9s %s (already exists)\n", actionSkip, path)
+
+	return false, nil
+}
+
+// askToOverwrite asks the user whether to overwrite the file at path.
+func askToOverwrite(path string) (bool, error) {
+	if !isTerminal(int(os.Stdin.Fd())) {
+		return false, errors.Errorf("file %s already exists, and there is no terminal to ask whether to "+
+			"overwrite it, set --on-conflict to another policy", path)
+	}
+
+	overwrite, err := pterm.DefaultInteractiveConfirm.Show(fmt.Sprintf("%s already exists, overwrite it?", path))
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to ask whether to overwrite file %s", path)
+	}
+
+	return overwrite, nil
+}
+
+// planConflict keeps path as a conflict for a dry run to report.
+func (fc *filesCreator) planConflict(path string, policy conflictPolicy) error {
+	plan, ok := fc.fs.(conflictPlanner)
+	if !ok {
+		return errors.Errorf("failed to plan file %s: a dry run needs a workspace", path)
+	}
+
+	plan.conflict(path, policy)
+
+	return nil
+}
+
+// policyFor returns the conflict policy for a file, its own overwrite setting or else that of the run.
+func policyFor(file *generate.File, onConflict conflictPolicy) (conflictPolicy, error) {
+	if file.GetOverwrite() == "" {
+		return onConflict, nil
+	}
+
+	return parseConflictPolicy(file.GetOverwrite())
+}
@@ -1,15 +1,65 @@
 package app
 
-import "github.com/spf13/afero"
+import (
+	"github.com/spf13/afero"
+
+	"github.com/kasulani/go-fundi/internal/generate"
+)
 
 func newFileReader(fs afero.Fs) *fileReader {
 	return &fileReader{fs: fs}
 }
 
-func newDirectoryCreator(fs afero.Fs) *directoryCreator {
-	return &directoryCreator{fs: fs}
+// newCache returns the cache in the cache directory of the config.
+func newCache(cfg *config) *cache {
+	return &cache{directory: cfg.CacheDir}
+}
+
+// newTemplatesResolver returns a templatesResolver that keeps what it fetches in the cache.
+func newTemplatesResolver(cfg *config, fs afero.Fs, cache *cache) *templatesResolver {
+	return &templatesResolver{fs: fs, cfg: cfg, cache: cache}
+}
+
+// newBlueprintResolver returns a blueprintResolver for the registry of the config.
+func newBlueprintResolver(cfg *config, reader *fileReader, resolver *templatesResolver) *blueprintResolver {
+	return &blueprintResolver{cfg: cfg, reader: reader, resolver: resolver}
+}
+
+func newWorkspace(base afero.Fs) *workspace {
+	layer := afero.NewMemMapFs()
+
+	return &workspace{
+		Fs:        afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(base), layer),
+		base:      base,
+		layer:     layer,
+		links:     make(map[string]string),
+		conflicts: make(map[string]conflictPolicy),
+	}
+}
+
+// newStagingWorkspace returns a workspace whose layer is a temporary directory on base.
+func newStagingWorkspace(base afero.Fs) (*workspace, error) {
+	staging, err := afero.TempDir(base, "", "fundi-")
+	if err != nil {
+		return nil, err
+	}
+
+	layer := afero.NewBasePathFs(base, staging)
+
+	return &workspace{
+		Fs:        afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(base), layer),
+		base:      base,
+		layer:     layer,
+		links:     make(map[string]string),
+		conflicts: make(map[string]conflictPolicy),
+		staging:   staging,
+	}, nil
 }
 
-func newFilesCreator(fs afero.Fs) *filesCreator {
-	return &filesCreator{fs: fs}
+// newProjectUseCase returns a ProjectUseCase that generates the project into fs, showing no progress when quiet.
+func newProjectUseCase(fs afero.Fs, cfg *config, quiet bool) *generate.ProjectUseCase {
+	return generate.NewProjectUseCase(
+		&directoryCreator{fs: fs, quiet: quiet},
+		&filesCreator{fs: fs, cfg: cfg, quiet: quiet},
+	)
 }
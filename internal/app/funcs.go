@@ -0,0 +1,358 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// irregularPlurals maps the singular of the nouns that don't follow the rules of pluralize to their plural.
+	irregularPlurals = map[string]string{
+		"child":  "children",
+		"foot":   "feet",
+		"goose":  "geese",
+		"man":    "men",
+		"mouse":  "mice",
+		"ox":     "oxen",
+		"person": "people",
+		"tooth":  "teeth",
+		"woman":  "women",
+	}
+
+	// uncountables are the nouns whose plural is the same as their singular.
+	uncountables = map[string]bool{
+		"data":        true,
+		"equipment":   true,
+		"fish":        true,
+		"information": true,
+		"metadata":    true,
+		"money":       true,
+		"news":        true,
+		"series":      true,
+		"sheep":       true,
+		"species":     true,
+	}
+
+	// ieNouns are the nouns that end in ie, whose plural ends in ies like that of the nouns that end in y.
+	ieNouns = map[string]bool{
+		"calorie": true,
+		"cookie":  true,
+		"genie":   true,
+		"hoodie":  true,
+		"movie":   true,
+		"pie":     true,
+		"rookie":  true,
+		"selfie":  true,
+		"tie":     true,
+		"zombie":  true,
+	}
+
+	// singularSuffixes maps the endings of plural nouns to the endings of their singulars.
+	singularSuffixes = []struct{ plural, singular string }{
+		{"ouses", "ouse"},
+		{"auses", "ause"},
+		{"uses", "us"},
+		{"sses", "ss"},
+		{"yses", "ysis"},
+		{"heses", "hesis"},
+		{"izzes", "iz"},
+		{"ezzes", "ez"},
+		{"zzes", "zz"},
+		{"tzes", "tz"},
+		{"zes", "ze"},
+		{"xes", "x"},
+		{"ches", "ch"},
+		{"shes", "sh"},
+		{"ies", "y"},
+		{"ss", "ss"},
+		{"us", "us"},
+		{"is", "is"},
+		{"s", ""},
+	}
+)
+
+// templateFuncs returns the functions that file templates and the values file can call.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"camelCase":    camelCase,
+		"pascalCase":   pascalCase,
+		"snakeCase":    snakeCase,
+		"kebabCase":    kebabCase,
+		"pluralize":    pluralize,
+		"singularize":  singularize,
+		"goIdentifier": goIdentifier,
+		"quote":        quote,
+		"indent":       indent,
+		"nindent":      nindent,
+		"default":      defaultValue,
+		"required":     required,
+		"toYaml":       toYaml,
+		"toJson":       toJSON,
+		"now":          time.Now,
+		"uuid":         newUUID,
+	}
+}
+
+// words splits s into the words of an identifier or a phrase.
+func words(s string) []string {
+	result := make([]string, 0)
+	runes := []rune(s)
+	start := -1
+
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			if start >= 0 {
+				result = append(result, string(runes[start:i]))
+				start = -1
+			}
+		case start < 0:
+			start = i
+		case startsWord(runes, i):
+			result = append(result, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		result = append(result, string(runes[start:]))
+	}
+
+	return result
+}
+
+// startsWord reports whether the letter or digit at i starts a new word.
+func startsWord(runes []rune, i int) bool {
+	if !unicode.IsUpper(runes[i]) {
+		return false
+	}
+
+	previous := runes[i-1]
+	nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+	return unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower)
+}
+
+// capitalize returns word in lower case, apart from its first letter.
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+
+	return string(runes)
+}
+
+func camelCase(s string) string {
+	pascal := []rune(pascalCase(s))
+	if len(pascal) > 0 {
+		pascal[0] = unicode.ToLower(pascal[0])
+	}
+
+	return string(pascal)
+}
+
+func pascalCase(s string) string {
+	builder := new(strings.Builder)
+	for _, word := range words(s) {
+		builder.WriteString(capitalize(word))
+	}
+
+	return builder.String()
+}
+
+func snakeCase(s string) string {
+	return strings.ToLower(strings.Join(words(s), "_"))
+}
+
+func kebabCase(s string) string {
+	return strings.ToLower(strings.Join(words(s), "-"))
+}
+
+// pluralize returns the plural of an English noun, keeping the case of its first letter.
+func pluralize(noun string) string {
+	lower := strings.ToLower(noun)
+
+	if uncountables[lower] {
+		return noun
+	}
+
+	if plural, ok := irregularPlurals[lower]; ok {
+		return matchCase(noun, plural)
+	}
+
+	switch {
+	case hasAnySuffix(lower, "iz", "ez"):
+		return noun + "zes"
+	case strings.HasSuffix(lower, "is"):
+		return noun[:len(noun)-2] + "es"
+	case hasAnySuffix(lower, "s", "x", "z", "ch", "sh"):
+		return noun + "es"
+	case strings.HasSuffix(lower, "y") && !hasAnySuffix(lower, "ay", "ey", "iy", "oy", "uy"):
+		return noun[:len(noun)-1] + "ies"
+	}
+
+	return noun + "s"
+}
+
+// singularize returns the singular of an English noun, keeping the case of its first letter.
+func singularize(noun string) string {
+	lower := strings.ToLower(noun)
+
+	if uncountables[lower] {
+		return noun
+	}
+
+	for singular, plural := range irregularPlurals {
+		if lower == plural {
+			return matchCase(noun, singular)
+		}
+	}
+
+	if strings.HasSuffix(lower, "ies") && ieNouns[strings.TrimSuffix(lower, "s")] {
+		return noun[:len(noun)-1]
+	}
+
+	for _, suffix := range singularSuffixes {
+		if strings.HasSuffix(lower, suffix.plural) {
+			return noun[:len(noun)-len(suffix.plural)] + suffix.singular
+		}
+	}
+
+	return noun
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchCase returns word with its first letter in upper case when the first letter of like is.
+func matchCase(like, word string) string {
+	if first := []rune(like); len(first) > 0 && unicode.IsUpper(first[0]) {
+		return capitalize(word)
+	}
+
+	return word
+}
+
+// goIdentifier turns s into a valid Go identifier.
+func goIdentifier(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			runes[i] = '_'
+		}
+	}
+
+	identifier := string(runes)
+
+	switch {
+	case identifier == "":
+		return "_"
+	case unicode.IsDigit(runes[0]):
+		return "_" + identifier
+	case token.IsKeyword(identifier):
+		return identifier + "_"
+	}
+
+	return identifier
+}
+
+func quote(value any) string {
+	return strconv.Quote(fmt.Sprint(value))
+}
+
+// indent puts spaces in front of every line of s.
+func indent(spaces int, s string) string {
+	padding := strings.Repeat(" ", spaces)
+
+	return padding + strings.ReplaceAll(s, "\n", "\n"+padding)
+}
+
+// nindent is indent on a new line, for indenting a block that starts on the line after the action.
+func nindent(spaces int, s string) string {
+	return "\n" + indent(spaces, s)
+}
+
+// defaultValue returns value, or fallback when value is missing or empty.
+func defaultValue(fallback, value any) any {
+	if isEmpty(value) {
+		return fallback
+	}
+
+	return value
+}
+
+// required returns value, or fails the template with message when value is missing or empty.
+func required(message string, value any) (any, error) {
+	if isEmpty(value) {
+		return nil, errors.New(message)
+	}
+
+	return value, nil
+}
+
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+
+	return v.IsZero()
+}
+
+func toYaml(value any) (string, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+func toJSON(value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// newUUID returns a random, version 4, UUID.
+func newUUID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]), nil
+}
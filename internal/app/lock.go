@@ -0,0 +1,246 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kasulani/go-fundi/internal/generate"
+)
+
+// lockFileName is the name of the manifest fundi writes into the output directory after generating a project.
+const lockFileName = ".fundi.lock"
+
+type (
+	// lockFile records how a project was generated, and what fundi generated into it.
+	lockFile struct {
+		Config      lockedConfig    `yaml:"config"`
+		Variables   map[string]any  `yaml:"variables,omitempty"`
+		Templates   lockedTemplates `yaml:"templates"`
+		Directories []string        `yaml:"directories,omitempty"`
+		Files       []*lockedFile   `yaml:"files,omitempty"`
+	}
+
+	lockedConfig struct {
+		Path   string `yaml:"path"`
+		SHA256 string `yaml:"sha256"`
+	}
+
+	// lockedTemplates names where the templates came from, and the version they were read at.
+	lockedTemplates struct {
+		Source  string `yaml:"source"`
+		Version string `yaml:"version,omitempty"`
+	}
+
+	// lockedFile is a generated file with the hash of its contents, or a symbolic link with its target.
+	lockedFile struct {
+		Path    string `yaml:"path"`
+		SHA256  string `yaml:"sha256,omitempty"`
+		Symlink string `yaml:"symlink,omitempty"`
+	}
+)
+
+// writeLockFile writes the manifest of a project generated from yamlFile into its output directory.
+func writeLockFile(fs afero.Fs, yamlFile *yamlFile, project *generate.Project, created []string) error {
+	lock, err := newLockFile(fs, yamlFile, project)
+	if err != nil {
+		return err
+	}
+
+	if lock.Directories, err = lockedDirectories(fs, yamlFile.Metadata.Output, created); err != nil {
+		return err
+	}
+
+	return lock.write(fs, yamlFile.Metadata.Output)
+}
+
+// write writes the manifest into output.
+func (lock *lockFile) write(fs afero.Fs, output string) error {
+	buffer := new(bytes.Buffer)
+
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(lock); err != nil {
+		return errors.Wrap(err, "failed to marshal the lock file")
+	}
+
+	path := filepath.Join(output, lockFileName)
+	if err := afero.WriteFile(fs, path, buffer.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write lock file %s", path)
+	}
+
+	return nil
+}
+
+func newLockFile(fs afero.Fs, yamlFile *yamlFile, project *generate.Project) (*lockFile, error) {
+	output := yamlFile.Metadata.Output
+	names := sortedFileNames(project.GetFiles())
+
+	lock := &lockFile{
+		Config:    lockedConfig{Path: yamlFile.path, SHA256: yamlFile.hash},
+		Variables: yamlFile.Metadata.Variables,
+		Templates: lockedTemplates{Source: yamlFile.Metadata.Templates},
+		Files:     make([]*lockedFile, 0, len(names)),
+	}
+
+	lock.Templates.Version = yamlFile.templatesVersion
+	if lock.Templates.Version == "" {
+		version, err := templatesDigest(fs, yamlFile.templates(), project.GetFiles())
+		if err != nil {
+			return nil, err
+		}
+
+		lock.Templates.Version = version
+	}
+
+	for _, name := range names {
+		if target := project.GetFiles()[name].GetSymlink(); target != "" {
+			if err := lock.addSymlink(fs, output, name, target); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		data, err := readRecord(fs, output, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the record of file %s", name)
+		}
+
+		if data != nil {
+			lock.Files = append(lock.Files, &lockedFile{Path: name, SHA256: digest(data)})
+		}
+	}
+
+	return lock, nil
+}
+
+// lockedDirectories returns the directories under output that fundi created.
+func lockedDirectories(fs afero.Fs, output string, created []string) ([]string, error) {
+	directories := slices.Clone(created)
+
+	exists, err := afero.Exists(fs, filepath.Join(output, lockFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		previous, err := readLockFile(fs, output)
+		if err != nil {
+			return nil, err
+		}
+
+		directories = append(directories, previous.Directories...)
+	}
+
+	slices.Sort(directories)
+
+	return slices.Compact(directories), nil
+}
+
+// createdDirectories returns the directories that changes create under output, relative to it.
+func createdDirectories(output string, list changes) []string {
+	directories := make([]string, 0)
+
+	for _, c := range list {
+		if !c.isDir || c.action != actionCreate {
+			continue
+		}
+
+		if directory, err := filepath.Rel(output, c.path); err == nil && directory != "." {
+			directories = append(directories, directory)
+		}
+	}
+
+	return directories
+}
+
+// addSymlink lists the symbolic link at name, when fundi created it.
+func (lock *lockFile) addSymlink(fs afero.Fs, output, name, target string) error {
+	current, err := readSymlink(fs, filepath.Join(output, name))
+	if err != nil {
+		return errors.Wrapf(err, "failed to read symlink %s", name)
+	}
+
+	if current == target {
+		lock.Files = append(lock.Files, &lockedFile{Path: name, Symlink: target})
+	}
+
+	return nil
+}
+
+// templatesDigest returns a digest of the names and contents of the templates the files are generated from.
+func templatesDigest(fs afero.Fs, templates string, files generate.FileTemplates) (string, error) {
+	used := make(map[string]bool)
+	for _, file := range files {
+		if file.GetTemplate() != "" {
+			used[file.GetTemplate()] = true
+		}
+	}
+
+	if len(used) == 0 {
+		return "", nil
+	}
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		data, err := afero.ReadFile(fs, filepath.Join(templates, name))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read template %s", name)
+		}
+
+		hash.Write([]byte(name + "\x00" + digest(data) + "\n"))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func sortedFileNames(files generate.FileTemplates) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// digest returns the hex encoded sha256 hash of data.
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// readLockFile returns the manifest of the project generated into output.
+func readLockFile(fs afero.Fs, output string) (*lockFile, error) {
+	path := filepath.Join(output, lockFileName)
+
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read lock file %s", path)
+	}
+
+	var lock lockFile
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal lock file %s", path)
+	}
+
+	return &lock, nil
+}
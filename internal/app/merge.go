@@ -0,0 +1,161 @@
+package app
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type (
+	// anchor is a line of base that both versions kept, by its index in base, ours and theirs.
+	anchor struct {
+		base, ours, theirs int
+	}
+)
+
+const (
+	conflictOursMarker   = "<<<<<<< ours"
+	conflictMarker       = "======="
+	conflictTheirsMarker = ">>>>>>> theirs"
+)
+
+// merge three-way merges ours and theirs, which both descend from base, and returns the number of conflicts.
+func merge(base, ours, theirs []byte) ([]byte, int) {
+	if isBinary(base) || isBinary(ours) || isBinary(theirs) {
+		return mergeBinary(base, ours, theirs)
+	}
+
+	baseLines, ourLines, theirLines := lines(base), lines(ours), lines(theirs)
+
+	merged := new(strings.Builder)
+	conflicts := 0
+	i, j, k := 0, 0, 0
+
+	for _, next := range anchors(baseLines, ourLines, theirLines) {
+		if !mergeChunk(merged, baseLines[i:next.base], ourLines[j:next.ours], theirLines[k:next.theirs]) {
+			conflicts++
+		}
+
+		if next.base < len(baseLines) {
+			merged.WriteString(baseLines[next.base])
+		}
+
+		i, j, k = next.base+1, next.ours+1, next.theirs+1
+	}
+
+	return []byte(merged.String()), conflicts
+}
+
+// anchors returns the lines of base that both versions kept, followed by the ends of all three.
+func anchors(base, ours, theirs []string) []anchor {
+	ourMatches, theirMatches := matches(base, ours), matches(base, theirs)
+	result := make([]anchor, 0)
+
+	for i := range base {
+		ourLine, inOurs := ourMatches[i]
+		theirLine, inTheirs := theirMatches[i]
+
+		if inOurs && inTheirs {
+			result = append(result, anchor{base: i, ours: ourLine, theirs: theirLine})
+		}
+	}
+
+	return append(result, anchor{base: len(base), ours: len(ours), theirs: len(theirs)})
+}
+
+// mergeChunk writes the lines that replace a chunk of base, and reports whether they merged without a conflict.
+func mergeChunk(merged *strings.Builder, base, ours, theirs []string) bool {
+	switch {
+	case slices.Equal(ours, base):
+		writeLines(merged, theirs)
+	case slices.Equal(theirs, base), slices.Equal(ours, theirs):
+		writeLines(merged, ours)
+	default:
+		writeConflict(merged, ours, theirs)
+
+		return false
+	}
+
+	return true
+}
+
+// writeConflict writes both versions of a chunk to merged, between conflict markers.
+func writeConflict(merged *strings.Builder, ours, theirs []string) {
+	merged.WriteString(conflictOursMarker + "\n")
+	writeConflictLines(merged, ours)
+	merged.WriteString(conflictMarker + "\n")
+	writeConflictLines(merged, theirs)
+	merged.WriteString(conflictTheirsMarker + "\n")
+}
+
+// hasConflictMarkers reports whether data holds the conflict markers of a merge that was not resolved.
+func hasConflictMarkers(data []byte) bool {
+	for _, line := range lines(data) {
+		switch strings.TrimSuffix(line, "\n") {
+		case conflictOursMarker, conflictTheirsMarker:
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches maps each line of base to the line of version it is kept as.
+func matches(base, version []string) map[int]int {
+	matched := make(map[int]int)
+
+	for _, block := range difflib.NewMatcherWithJunk(base, version, false, nil).GetMatchingBlocks() {
+		for offset := 0; offset < block.Size; offset++ {
+			matched[block.A+offset] = block.B + offset
+		}
+	}
+
+	return matched
+}
+
+// lines splits data into lines that keep their newline.
+func lines(data []byte) []string {
+	split := strings.SplitAfter(string(data), "\n")
+	if last := len(split) - 1; split[last] == "" {
+		return split[:last]
+	}
+
+	return split
+}
+
+// writeLines writes lines to merged.
+func writeLines(merged *strings.Builder, lines []string) {
+	for _, line := range lines {
+		merged.WriteString(line)
+	}
+}
+
+// writeConflictLines writes one side of a conflict to merged, ending it with a newline.
+func writeConflictLines(merged *strings.Builder, lines []string) {
+	writeLines(merged, lines)
+
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		merged.WriteString("\n")
+	}
+}
+
+// mergeBinary merges versions of a file that has no lines to merge, such as an image.
+func mergeBinary(base, ours, theirs []byte) ([]byte, int) {
+	switch {
+	case bytes.Equal(base, ours):
+		return theirs, 0
+	case bytes.Equal(base, theirs), bytes.Equal(ours, theirs):
+		return ours, 0
+	}
+
+	return ours, 1
+}
+
+// isBinary reports whether data is binary rather than text, the way git guesses it: by a NUL byte near its start.
+func isBinary(data []byte) bool {
+	const sniffLength = 8000
+
+	return bytes.IndexByte(data[:min(len(data), sniffLength)], 0) >= 0
+}
@@ -0,0 +1,19 @@
+package app
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parseMode returns the permissions written in octal in value, such as 0755, 755 or 0o755.
+func parseMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(strings.TrimPrefix(value, "0o"), 8, 32)
+	if err != nil || mode > uint64(os.ModePerm) {
+		return 0, errors.Errorf("invalid mode %q, use octal permissions such as 0755", value)
+	}
+
+	return os.FileMode(mode), nil
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+const (
+	// stateDirectory holds what fundi keeps about a project it generated, under the output directory.
+	stateDirectory = ".fundi"
+
+	// recordDirectory holds every file as fundi last generated it, under the state directory.
+	recordDirectory = "generated"
+)
+
+// recordPath returns where the last generated version of the file at name, relative to output, is kept.
+func recordPath(output, name string) string {
+	return filepath.Join(output, stateDirectory, recordDirectory, name)
+}
+
+// readRecord returns the last generated version of the file at name, or nil when fundi has no record of it.
+func readRecord(fs afero.Fs, output, name string) ([]byte, error) {
+	data, err := afero.ReadFile(fs, recordPath(output, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+// writeRecord keeps data as the last generated version of the file at name.
+func writeRecord(fs afero.Fs, output, name string, data []byte) error {
+	path := recordPath(output, name)
+
+	if err := fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "failed to record file %s", name)
+	}
+
+	if err := afero.WriteFile(fs, path, data, 0644); err != nil {
+		return errors.Wrapf(err, "failed to record file %s", name)
+	}
+
+	return nil
+}
+
+// removeRecord forgets the last generated version of the file at name.
+func removeRecord(fs afero.Fs, output, name string) error {
+	if err := fs.Remove(recordPath(output, name)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove the record of file %s", name)
+	}
+
+	return nil
+}
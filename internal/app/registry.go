@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// registry is a YAML or JSON index of blueprints, by name and then by version.
+	registry struct {
+		Blueprints map[string]map[string]*blueprint `yaml:"blueprints"`
+	}
+
+	// blueprint is a version of a blueprint, along with the checksums of its templates and values.
+	blueprint struct {
+		Config    string    `yaml:"config"`
+		Templates string    `yaml:"templates"`
+		Values    string    `yaml:"values"`
+		SHA256    checksums `yaml:"sha256"`
+	}
+
+	// blueprintResolver turns a reference to a blueprint into the config file of a project.
+	blueprintResolver struct {
+		cfg      *config
+		reader   *fileReader
+		resolver *templatesResolver
+	}
+)
+
+// blueprintVersionSeparator separates the name of a blueprint from its version in a reference to it.
+const blueprintVersionSeparator = "@"
+
+// resolveBlueprint returns the config file of the blueprint reference names, with its templates and values fetched.
+func (br *blueprintResolver) resolveBlueprint(ctx context.Context, reference, output string) (*yamlFile, error) {
+	name, version, _ := strings.Cut(reference, blueprintVersionSeparator)
+
+	if br.cfg.Registry == "" {
+		if version != "" {
+			return nil, errors.Errorf("built-in blueprint %s has no versions, set a registry with --registry or "+
+				"FUNDI_REGISTRY to use %s", name, reference)
+		}
+
+		return br.resolveBuiltinBlueprint(name, output)
+	}
+
+	version, entry, err := br.findBlueprint(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	path := relativeTo(br.cfg.Registry, entry.Config)
+
+	yamlFile, err := loadConfigFile(ctx, br.reader, br.resolver, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read blueprint %s@%s", name, version)
+	}
+
+	br.applyBlueprint(yamlFile, entry, path, output)
+
+	if err := br.resolver.resolveSources(ctx, yamlFile); err != nil {
+		return nil, errors.Wrapf(err, "failed to read blueprint %s@%s", name, version)
+	}
+
+	return yamlFile, nil
+}
+
+// findBlueprint returns the version of the blueprint name in the registry, or its latest version.
+func (br *blueprintResolver) findBlueprint(ctx context.Context, name, version string) (string, *blueprint, error) {
+	registry, err := br.readRegistry(ctx, br.cfg.Registry)
+	if err != nil {
+		return "", nil, err
+	}
+
+	version, entry, err := registry.find(name, version)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if entry.Config == "" {
+		return "", nil, errors.Errorf("blueprint %s@%s names no config file", name, version)
+	}
+
+	return version, entry, nil
+}
+
+// applyBlueprint sets the config file of a blueprint to generate the project into output.
+func (br *blueprintResolver) applyBlueprint(yamlFile *yamlFile, entry *blueprint, path, output string) {
+	if yamlFile.Metadata == nil {
+		yamlFile.Metadata = new(metadata)
+	}
+
+	metadata := yamlFile.Metadata
+	if output != "" {
+		metadata.Output = output
+	}
+
+	metadata.Templates = relativeTo(path, metadata.Templates)
+	metadata.Values = relativeTo(path, metadata.Values)
+
+	if entry.Templates != "" || entry.SHA256.Templates != "" {
+		metadata.SHA256.Templates = entry.SHA256.Templates
+	}
+
+	if entry.Templates != "" {
+		metadata.Templates = relativeTo(br.cfg.Registry, entry.Templates)
+	}
+
+	if entry.Values != "" || entry.SHA256.Values != "" {
+		metadata.SHA256.Values = entry.SHA256.Values
+	}
+
+	if entry.Values != "" {
+		metadata.Values = relativeTo(br.cfg.Registry, entry.Values)
+	}
+}
+
+// readRegistry reads the registry at location, downloading it when location is a URL.
+func (br *blueprintResolver) readRegistry(ctx context.Context, location string) (*registry, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if isURL(location) {
+		data, err = br.resolver.downloadFile(ctx, registryCacheDirectory, "registry", location)
+	} else {
+		data, err = afero.ReadFile(br.reader.fs, location)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read registry %s", location)
+	}
+
+	var index registry
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal registry %s", location)
+	}
+
+	return &index, nil
+}
+
+// find returns the version of the blueprint name, or its latest version when version is empty.
+func (r *registry) find(name, version string) (string, *blueprint, error) {
+	versions, ok := r.Blueprints[name]
+	if !ok || len(versions) == 0 {
+		return "", nil, errors.Errorf("blueprint %s is not in the registry", name)
+	}
+
+	available := make([]string, 0, len(versions))
+	for v := range versions {
+		available = append(available, v)
+	}
+
+	sort.Slice(available, func(i, j int) bool { return compareVersions(available[i], available[j]) < 0 })
+
+	if version == "" {
+		version = available[len(available)-1]
+	}
+
+	entry, ok := versions[version]
+	if !ok || entry == nil {
+		return "", nil, errors.Errorf("blueprint %s has no version %s, use one of %s", name, version,
+			strings.Join(available, ", "))
+	}
+
+	return version, entry, nil
+}
+
+// compareVersions compares two versions such as 2.1 and 2.10 part by part.
+func compareVersions(a, b string) int {
+	left := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	right := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(left) && i < len(right); i++ {
+		l, lErr := strconv.Atoi(left[i])
+		r, rErr := strconv.Atoi(right[i])
+
+		switch {
+		case lErr == nil && rErr == nil && l != r:
+			return l - r
+		case (lErr != nil || rErr != nil) && left[i] != right[i]:
+			return strings.Compare(left[i], right[i])
+		}
+	}
+
+	return len(left) - len(right)
+}
+
+// relativeTo returns source, which is relative to the file at base, as a path or URL of its own.
+func relativeTo(base, source string) string {
+	if source == "" || strings.Contains(source, "://") || filepath.IsAbs(source) {
+		return source
+	}
+
+	if isURL(base) {
+		return base[:strings.LastIndex(base, "/")+1] + strings.TrimPrefix(source, "./")
+	}
+
+	location, subpath, found := strings.Cut(source, "//")
+
+	path := filepath.Join(filepath.Dir(base), location)
+	if found {
+		path += "//" + subpath
+	}
+
+	return path
+}
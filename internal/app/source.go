@@ -0,0 +1,303 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+type (
+	// templatesResolver fetches the config file, templates and values of a project.
+	templatesResolver struct {
+		fs    afero.Fs
+		cfg   *config
+		cache *cache
+	}
+
+	// gitSource is a git repository to read templates from, at a ref, and optionally from a subdirectory of it.
+	gitSource struct {
+		repository   string
+		subdirectory string
+		ref          string
+	}
+)
+
+const (
+	// gitScheme prefixes the URL of a git repository that holds templates, such as git+https://host/org/repo.git.
+	gitScheme = "git+"
+
+	// gitCacheDirectory holds the git repositories templates are fetched from, under the cache directory.
+	gitCacheDirectory = "git"
+
+	// gitMirrorDirectory holds the mirror of a git repository, next to its checkouts.
+	gitMirrorDirectory = "repository.git"
+
+	// defaultGitRef is what the templates of a git source are read at when it names no ref.
+	defaultGitRef = "HEAD"
+)
+
+var (
+	// downloadClient fetches config files, registries and archives, giving up on those that take too long.
+	downloadClient = &http.Client{Timeout: 5 * time.Minute}
+
+	// maxDownloadSize is the number of bytes past which a download fails.
+	maxDownloadSize int64 = 512 << 20
+)
+
+// resolveSources fetches the remote or archived templates and values of yamlFile.
+func (resolver *templatesResolver) resolveSources(ctx context.Context, yamlFile *yamlFile) error {
+	templates := yamlFile.Metadata.Templates
+	if strings.HasPrefix(templates, gitScheme) {
+		if err := resolver.resolveGit(ctx, yamlFile); err != nil {
+			return err
+		}
+	} else {
+		path, version, err := resolver.resolveArchive(ctx, yamlFile, templates, yamlFile.Metadata.SHA256.Templates)
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch templates from %s", templates)
+		}
+
+		yamlFile.templatesPath, yamlFile.templatesVersion = path, version
+	}
+
+	values := yamlFile.Metadata.Values
+
+	path, _, err := resolver.resolveArchive(ctx, yamlFile, values, yamlFile.Metadata.SHA256.Values)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch values from %s", values)
+	}
+
+	if path != "" {
+		if isDir, _ := afero.IsDir(yamlFile.sources, path); isDir {
+			return errors.Errorf("values source %s is a directory, use a source such as bundle.tar.gz//values.yml", values)
+		}
+	}
+
+	yamlFile.valuesPath = path
+
+	return nil
+}
+
+// downloadConfigFile returns the config file at location, which is an http or https URL.
+func (resolver *templatesResolver) downloadConfigFile(ctx context.Context, location string) (*yamlFile, error) {
+	data, err := resolver.downloadFile(ctx, configCacheDirectory, "config file", location)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseYAMLFile(location, data)
+}
+
+// downloadFile returns the file at the http or https URL location, through the cache.
+func (resolver *templatesResolver) downloadFile(ctx context.Context, kind, what, location string) ([]byte, error) {
+	key := cacheKey(location)
+
+	data, err := resolver.cache.load(kind, key)
+	switch {
+	case err != nil:
+		return nil, err
+	case resolver.cfg.Offline && data == nil:
+		return nil, errors.Errorf("%s %s is not in the cache, %s", what, location, offlineHint)
+	case !resolver.cfg.Offline:
+		if data, err = download(ctx, location); err != nil {
+			return nil, errors.Wrapf(err, "failed to download %s %s", what, location)
+		}
+
+		if err := resolver.cache.store(kind, key, location, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// resolveGit fetches the templates of yamlFile from git, and points yamlFile at the checkout and the commit it is at.
+func (resolver *templatesResolver) resolveGit(ctx context.Context, yamlFile *yamlFile) error {
+	source := yamlFile.Metadata.Templates
+
+	git, err := parseGitSource(source)
+	if err != nil {
+		return err
+	}
+
+	path, commit, err := resolver.fetchGit(ctx, git)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch templates from %s", source)
+	}
+
+	yamlFile.templatesPath = filepath.Join(path, git.subdirectory)
+	yamlFile.templatesVersion = commit
+
+	return nil
+}
+
+// parseGitSource parses a git source such as git+https://host/org/blueprints.git//go-service?ref=v2.3.
+func parseGitSource(source string) (*gitSource, error) {
+	location, query, _ := strings.Cut(strings.TrimPrefix(source, gitScheme), "?")
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid templates source %s", source)
+	}
+
+	scheme, rest, ok := strings.Cut(location, "://")
+	if !ok || scheme == "" {
+		return nil, errors.Errorf("invalid templates source %s, use a URL such as git+https://host/repo.git", source)
+	}
+
+	repository, subdirectory, _ := strings.Cut(rest, "//")
+	if subdirectory != "" && !filepath.IsLocal(subdirectory) {
+		return nil, errors.Errorf("invalid templates source %s, subdirectory %s is outside the repository", source,
+			subdirectory)
+	}
+
+	ref := values.Get("ref")
+	if ref == "" {
+		ref = defaultGitRef
+	}
+
+	return &gitSource{repository: scheme + "://" + repository, subdirectory: subdirectory, ref: ref}, nil
+}
+
+// fetchGit returns the checkout of the repository of source at its ref, and the commit the ref resolved to.
+func (resolver *templatesResolver) fetchGit(ctx context.Context, source *gitSource) (string, string, error) {
+	cacheDirectory, err := resolver.cache.path()
+	if err != nil {
+		return "", "", err
+	}
+
+	directory := filepath.Join(cacheDirectory, gitCacheDirectory, cacheKey(source.repository))
+	mirror := filepath.Join(directory, gitMirrorDirectory)
+
+	if err := resolver.syncMirror(ctx, source.repository, mirror); err != nil {
+		return "", "", err
+	}
+
+	commit, err := runGit(ctx, mirror, "rev-parse", "--verify", "--quiet", source.ref+"^{commit}")
+	switch {
+	case err != nil && resolver.cfg.Offline:
+		return "", "", errors.Errorf("ref %s is not in the cache, %s", source.ref, offlineHint)
+	case err != nil:
+		return "", "", errors.Errorf("ref %s does not exist", source.ref)
+	}
+
+	checkout := filepath.Join(directory, commit)
+	if _, err := os.Stat(checkout); err == nil {
+		return checkout, commit, touch(checkout)
+	}
+
+	return checkout, commit, checkoutGit(ctx, mirror, commit, checkout)
+}
+
+// syncMirror mirrors repository into the directory mirror, or fetches it when it is already there.
+func (resolver *templatesResolver) syncMirror(ctx context.Context, repository, mirror string) error {
+	_, err := os.Stat(mirror)
+
+	switch {
+	case os.IsNotExist(err) && resolver.cfg.Offline:
+		return errors.Errorf("repository %s is not in the cache, %s", repository, offlineHint)
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(mirror), 0755); err != nil {
+			return err
+		}
+
+		_, err = runGit(ctx, "", "clone", "--quiet", "--mirror", repository, mirror)
+	case err == nil && !resolver.cfg.Offline:
+		_, err = runGit(ctx, mirror, "fetch", "--quiet", "--prune", "origin")
+	}
+
+	return err
+}
+
+// checkoutGit checks commit out of the mirror into the directory checkout, without the repository itself.
+func checkoutGit(ctx context.Context, mirror, commit, checkout string) error {
+	staging, err := os.MkdirTemp(filepath.Dir(checkout), commit+"-")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.RemoveAll(staging) }()
+
+	if _, err := runGit(ctx, "", "clone", "--quiet", "--shared", "--no-checkout", mirror, staging); err != nil {
+		return err
+	}
+
+	if _, err := runGit(ctx, staging, "checkout", "--quiet", "--detach", commit); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Join(staging, ".git")); err != nil {
+		return err
+	}
+
+	return os.Rename(staging, checkout)
+}
+
+// mountDirectory returns where the archives and blueprints read into memory are mounted.
+func mountDirectory(elements ...string) string {
+	return filepath.Join(append([]string{os.TempDir(), "fundi-sources"}, elements...)...)
+}
+
+// download returns what is at location, which is an http or https URL.
+func download(ctx context.Context, location string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := downloadClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("GET %s: %s", location, response.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxDownloadSize {
+		return nil, errors.Errorf("GET %s: larger than %d bytes", location, maxDownloadSize)
+	}
+
+	return data, nil
+}
+
+// runGit runs git with args in directory, and returns what it printed.
+func runGit(ctx context.Context, directory string, args ...string) (string, error) {
+	if directory != "" {
+		args = append([]string{"-C", directory}, args...)
+	}
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+
+		return "", errors.Errorf("git %s: %s", strings.Join(args, " "), message)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
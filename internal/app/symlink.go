@@ -0,0 +1,143 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+type (
+	// symlinker is a file system that keeps the symbolic links written to it apart from its files.
+	symlinker interface {
+		symlink(target, path string) error
+		readlink(path string) (string, error)
+	}
+)
+
+// createSymlink links path to target, unless something else is in the way and the policy keeps it.
+func (fc *filesCreator) createSymlink(path, target string, policy conflictPolicy) error {
+	links, ok := fc.fs.(symlinker)
+	if !ok {
+		return errors.Errorf("failed to create symlink %s: symlinks are not supported", path)
+	}
+
+	current, err := links.readlink(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read symlink %s", path)
+	}
+
+	if current == target {
+		return links.symlink(target, path)
+	}
+
+	exists, err := afero.Exists(fc.fs, path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case current == "" && !exists, current != "" && policy == conflictOverwrite:
+		return links.symlink(target, path)
+	case fc.cfg.DryRun:
+		if err := fc.planConflict(path, policy); err != nil {
+			return err
+		}
+
+		return links.symlink(target, path)
+	case policy == conflictFail:
+		return errors.Errorf("file %s already exists", path)
+	}
+
+	fmt.Printf("%-9s %s (already exists)\n", actionSkip, path)
+
+	return nil
+}
+
+// readSymlink returns the target of the symbolic link at path, or an empty string when path is not one.
+func readSymlink(fs afero.Fs, path string) (string, error) {
+	if links, ok := fs.(symlinker); ok {
+		return links.readlink(path)
+	}
+
+	lstater, isLstater := fs.(afero.Lstater)
+	reader, isReader := fs.(afero.LinkReader)
+
+	if !isLstater || !isReader {
+		return "", nil
+	}
+
+	info, _, err := lstater.LstatIfPossible(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", nil
+	}
+
+	return reader.ReadlinkIfPossible(path)
+}
+
+// writeSymlink links path to target in fs, replacing the symbolic link that is there.
+func writeSymlink(fs afero.Fs, path, target string) error {
+	linker, ok := fs.(afero.Linker)
+	if !ok {
+		return errors.Errorf("failed to create symlink %s: the file system does not support symlinks", path)
+	}
+
+	if err := fs.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to replace symlink %s", path)
+	}
+
+	if err := linker.SymlinkIfPossible(target, path); err != nil {
+		return errors.Wrapf(err, "failed to create symlink %s", path)
+	}
+
+	return nil
+}
+
+// updateSymlink applies the change of a symbolic link to the file system, and returns what it did.
+func updateSymlink(fs afero.Fs, c *change) (string, error) {
+	if c.action == actionUnchanged {
+		return c.action, nil
+	}
+
+	return c.action, writeSymlink(fs, c.path, c.link)
+}
+
+// cleanSymlink removes a generated symbolic link, and reports whether it is gone.
+func cleanSymlink(fs afero.Fs, output string, file *lockedFile, force bool) (bool, error) {
+	path := filepath.Join(output, file.Path)
+
+	target, err := readSymlink(fs, path)
+	if err != nil {
+		return false, err
+	}
+
+	if target == "" {
+		if exists, err := afero.Exists(fs, path); err != nil || !exists {
+			return err == nil, err
+		}
+	}
+
+	if target != file.Symlink && !force {
+		fmt.Printf("%-9s %s (modified since it was generated)\n", actionKeep, path)
+
+		return false, nil
+	}
+
+	if err := fs.Remove(path); err != nil {
+		return false, err
+	}
+
+	fmt.Printf("%-9s %s\n", actionRemove, path)
+
+	return true, nil
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type (
+	// templateError is a file whose template failed to parse or execute, and where it failed.
+	templateError struct {
+		file     string
+		template string
+		line     int
+		column   int
+		key      string
+		message  string
+	}
+
+	// templateErrors holds every file whose template failed to render in a run.
+	templateErrors []*templateError
+)
+
+var (
+	// templateErrorPattern matches the errors text/template fails to parse or execute with.
+	templateErrorPattern = regexp.MustCompile(`^template: (.+?):(\d+)(?::(\d+))?: (.*)$`)
+	executingPattern     = regexp.MustCompile(`^executing ".*?" at <(.*?)>: (.*)$`)
+	keyPattern           = regexp.MustCompile(`^(?:\.[\w-]+)+$`)
+	missingKeyPattern    = regexp.MustCompile(`^map has no entry for key "(.*)"$`)
+	nilPointerPattern    = regexp.MustCompile(`^nil pointer evaluating .*\.([\w-]+)$`)
+)
+
+// newTemplateError returns the error that rendering file from templateName failed with.
+func newTemplateError(file, templateName string, err error) *templateError {
+	te := &templateError{file: file, template: templateName, message: err.Error()}
+
+	match := templateErrorPattern.FindStringSubmatch(te.message)
+	if match == nil {
+		return te
+	}
+
+	te.template, te.message = match[1], match[4]
+	te.line, _ = strconv.Atoi(match[2])
+	te.column, _ = strconv.Atoi(match[3])
+
+	if executing := executingPattern.FindStringSubmatch(te.message); executing != nil {
+		te.message = executing[2]
+
+		if keyPattern.MatchString(executing[1]) {
+			te.key = unresolvedKey(executing[1], te.message)
+		}
+	}
+
+	return te
+}
+
+// unresolvedKey returns key up to its first segment that could not be resolved.
+func unresolvedKey(key, message string) string {
+	segments := strings.Split(strings.TrimPrefix(key, "."), ".")
+
+	if missing := missingKeyPattern.FindStringSubmatch(message); missing != nil {
+		if i := slices.Index(segments, missing[1]); i >= 0 {
+			return "." + strings.Join(segments[:i+1], ".")
+		}
+	}
+
+	if nilPointer := nilPointerPattern.FindStringSubmatch(message); nilPointer != nil {
+		if i := slices.Index(segments, nilPointer[1]); i > 0 {
+			return "." + strings.Join(segments[:i], ".")
+		}
+	}
+
+	return key
+}
+
+func (te *templateError) Error() string {
+	location := te.template
+	if te.line > 0 {
+		location += fmt.Sprintf(":%d", te.line)
+	}
+
+	if te.column > 0 {
+		location += fmt.Sprintf(":%d", te.column)
+	}
+
+	message := fmt.Sprintf("%s: %s: %s", te.file, location, te.message)
+	if te.key != "" {
+		message += fmt.Sprintf(" (values key %s)", te.key)
+	}
+
+	return message
+}
+
+func (errs templateErrors) Error() string {
+	lines := make([]string, 0, len(errs)+1)
+
+	switch len(errs) {
+	case 1:
+		lines = append(lines, "1 template failed to render:")
+	default:
+		lines = append(lines, fmt.Sprintf("%d templates failed to render:", len(errs)))
+	}
+
+	for _, err := range errs {
+		lines = append(lines, "  "+err.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
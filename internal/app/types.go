@@ -5,7 +5,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/pterm/pterm"
@@ -21,11 +25,30 @@ type (
 		Templates string         `yaml:"templates"`
 		Values    string         `yaml:"values"`
 		Variables map[string]any `yaml:"variables"`
+		Strict    bool           `yaml:"strict"`
+		Copy      []string       `yaml:"copy"`
+		SHA256    checksums      `yaml:"sha256"`
+	}
+
+	// checksums are the sha256 checksums the archives the templates and values come from must match.
+	checksums struct {
+		Templates string `yaml:"templates"`
+		Values    string `yaml:"values"`
 	}
 
 	file struct {
-		Name     string `yaml:"name"`
-		Template string `yaml:"template"`
+		Name      string         `yaml:"name"`
+		Template  string         `yaml:"template"`
+		Skip      bool           `yaml:"skip"`
+		When      string         `yaml:"when"`
+		ForEach   string         `yaml:"foreach"`
+		Overwrite string         `yaml:"overwrite"`
+		Values    map[string]any `yaml:"values"`
+		Content   string         `yaml:"content"`
+		Copy      bool           `yaml:"copy"`
+		Raw       bool           `yaml:"raw"`
+		Mode      string         `yaml:"mode"`
+		Symlink   string         `yaml:"symlink"`
 	}
 
 	files []*file
@@ -34,6 +57,10 @@ type (
 		Name           string      `yaml:"name"`
 		Files          files       `yaml:"files"`
 		SubDirectories directories `yaml:"directories"`
+		Skip           bool        `yaml:"skip"`
+		When           string      `yaml:"when"`
+		ForEach        string      `yaml:"foreach"`
+		Mode           string      `yaml:"mode"`
 	}
 
 	directories []*directory
@@ -41,13 +68,55 @@ type (
 	yamlFile struct {
 		Metadata    *metadata   `yaml:"metadata"`
 		Directories directories `yaml:"directories"`
+
+		path string
+		hash string
+
+		// templatesPath, templatesVersion, valuesPath and sources are where fetched templates and values are read from.
+		templatesPath    string
+		templatesVersion string
+		valuesPath       string
+		sources          afero.Fs
 	}
 
 	fileReader struct{ fs afero.Fs }
 
-	directoryCreator struct{ fs afero.Fs }
+	directoryCreator struct {
+		fs    afero.Fs
+		quiet bool
+	}
+
+	filesCreator struct {
+		fs    afero.Fs
+		cfg   *config
+		quiet bool
+
+		// overwrite ignores the conflict policies, for a workspace to hold every file as it is generated.
+		overwrite bool
+	}
 
-	filesCreator struct{ fs afero.Fs }
+	// templateContext is what a template knows about the file it generates, as .Fundi.
+	templateContext struct {
+		Output     string
+		Path       string
+		Dir        string
+		ConfigFile string
+		Timestamp  time.Time
+	}
+
+	// progressbar shows the progress of generating directories or files on the terminal, unless it is quiet.
+	progressbar struct{ printer *pterm.ProgressbarPrinter }
+)
+
+const (
+	// partialsDirectory holds the partials under the templates directory.
+	partialsDirectory = "_partials"
+
+	// fundiKey is the key templates find their templateContext under.
+	fundiKey = "Fundi"
+
+	// valuesKey is the key templates find the values of their template under, when those are not a map.
+	valuesKey = "Values"
 )
 
 // readYAMLFile returns an instance of yamlFile.
@@ -57,34 +126,81 @@ func (fr *fileReader) readYAMLFile(filepath string) (*yamlFile, error) {
 		return nil, errors.Wrapf(err, "failed to read file %s", filepath)
 	}
 
+	return parseYAMLFile(filepath, data)
+}
+
+// parseYAMLFile returns the yamlFile in data, which was read from filepath.
+func parseYAMLFile(filepath string, data []byte) (*yamlFile, error) {
 	var cfg yamlFile
-	err = yaml.Unmarshal(data, &cfg)
+	err := yaml.Unmarshal(data, &cfg)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to unmarshal YAML data")
 	}
 
+	cfg.path, cfg.hash = filepath, digest(data)
+
 	return &cfg, nil
 }
 
 func (yf *yamlFile) toConfigurationFile() *generate.ConfigurationFile {
-	dirs := make(generate.Directories, len(yf.Directories))
-	for i, dir := range yf.Directories {
-		dirs[i] = generate.NewDirectory(dir.Name, yf.convertFiles(dir.Files), yf.convertDirectories(dir.SubDirectories))
-	}
-
 	return generate.NewConfigurationFile(
 		generate.NewMetadata(
 			map[string]any{
-				generate.MetaDataOutputKey:    yf.Metadata.Output,
-				generate.MetaDataTemplatesKey: yf.Metadata.Templates,
-				generate.MetaDataValuesKey:    yf.Metadata.Values,
-				generate.MetaDataVariablesKey: yf.Metadata.Variables,
+				generate.MetaDataOutputKey:     yf.Metadata.Output,
+				generate.MetaDataTemplatesKey:  yf.templates(),
+				generate.MetaDataValuesKey:     yf.values(),
+				generate.MetaDataVariablesKey:  yf.Metadata.Variables,
+				generate.MetaDataStrictKey:     yf.Metadata.Strict,
+				generate.MetaDataConfigFileKey: yf.path,
+				generate.MetaDataCopyKey:       yf.Metadata.Copy,
 			},
 		),
-		dirs,
+		yf.convertDirectories(yf.Directories),
 	)
 }
 
+// templates returns the local directory the templates are read from.
+func (yf *yamlFile) templates() string {
+	if yf.templatesPath != "" {
+		return yf.templatesPath
+	}
+
+	return yf.Metadata.Templates
+}
+
+// values returns the path the values file is read from.
+func (yf *yamlFile) values() string {
+	if yf.valuesPath != "" {
+		return yf.valuesPath
+	}
+
+	return yf.Metadata.Values
+}
+
+// setVariables sets the variables of yamlFile to the values in variables, over those the config file sets.
+func (yf *yamlFile) setVariables(variables map[string]string) {
+	if len(variables) == 0 {
+		return
+	}
+
+	if yf.Metadata.Variables == nil {
+		yf.Metadata.Variables = make(map[string]any, len(variables))
+	}
+
+	for name, value := range variables {
+		yf.Metadata.Variables[name] = value
+	}
+}
+
+// filesystem returns fs with what was unpacked from archives laid under it.
+func (yf *yamlFile) filesystem(fs afero.Fs) afero.Fs {
+	if yf.sources == nil {
+		return fs
+	}
+
+	return afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(yf.sources), fs)
+}
+
 func (yf *yamlFile) convertFiles(fs files) generate.Files {
 	if len(fs) == 0 {
 		return nil
@@ -92,7 +208,16 @@ func (yf *yamlFile) convertFiles(fs files) generate.Files {
 
 	files := make(generate.Files, len(fs))
 	for i, f := range fs {
-		files[i] = generate.NewFile(f.Name, f.Template)
+		files[i] = generate.NewFile(f.Name, f.Template).
+			WithSkip(f.Skip).
+			WithCondition(f.When).
+			WithForEach(f.ForEach).
+			WithOverwrite(f.Overwrite).
+			WithValues(f.Values).
+			WithContent(f.Content).
+			WithVerbatim(f.Copy || f.Raw).
+			WithMode(f.Mode).
+			WithSymlink(f.Symlink)
 	}
 
 	return files
@@ -105,7 +230,11 @@ func (yf *yamlFile) convertDirectories(ds directories) generate.Directories {
 
 	dirs := make(generate.Directories, len(ds))
 	for i, d := range ds {
-		dirs[i] = generate.NewDirectory(d.Name, yf.convertFiles(d.Files), yf.convertDirectories(d.SubDirectories))
+		dirs[i] = generate.NewDirectory(
+			d.Name,
+			yf.convertFiles(d.Files),
+			yf.convertDirectories(d.SubDirectories),
+		).WithSkip(d.Skip).WithCondition(d.When).WithForEach(d.ForEach).WithMode(d.Mode)
 	}
 
 	return dirs
@@ -115,32 +244,71 @@ func (creator *directoryCreator) CreateDirectoryStructure(
 	_ context.Context,
 	output string,
 	directories []string,
+	modes generate.DirectoryModes,
 ) error {
 	dirs := directories
 	if len(dirs) == 0 {
-		fmt.Println("no files to create")
+		creator.printf("no files to create\n")
 
 		return nil
 	}
 
-	bar, err := pterm.DefaultProgressbar.WithTotal(len(dirs)).WithTitle("Generating directories").Start()
+	pending, err := creator.pendingModes(output, modes)
+	if err != nil {
+		return err
+	}
+
+	bar, err := startProgressbar(creator.quiet, len(dirs), "Generating directories")
 	if err != nil {
 		return err
 	}
 
 	for _, dir := range dirs {
 		if err := creator.fs.MkdirAll(output+string(os.PathSeparator)+dir, 0755); err != nil {
+			_ = bar.stop()
+
 			return errors.Wrapf(err, "failed to create directory %s", dir)
 		}
-		bar.Increment()
+		bar.increment()
 	}
 
-	_, err = bar.Stop()
-	if err != nil {
-		return err
+	for path, mode := range pending {
+		if err := creator.fs.Chmod(path, mode); err != nil {
+			_ = bar.stop()
+
+			return errors.Wrapf(err, "failed to set the mode of directory %s", path)
+		}
 	}
 
-	return nil
+	return bar.stop()
+}
+
+// pendingModes returns the permissions to set on the directories that don't exist yet.
+func (creator *directoryCreator) pendingModes(
+	output string,
+	modes generate.DirectoryModes,
+) (map[string]os.FileMode, error) {
+	pending := make(map[string]os.FileMode, len(modes))
+
+	for dir, value := range modes {
+		mode, err := parseMode(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid mode on directory %s", dir)
+		}
+
+		path := filepath.Clean(output + string(os.PathSeparator) + dir)
+
+		exists, err := afero.DirExists(creator.fs, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			pending[path] = mode
+		}
+	}
+
+	return pending, nil
 }
 
 func (fc *filesCreator) CreateFiles(
@@ -149,80 +317,405 @@ func (fc *filesCreator) CreateFiles(
 	templateFiles generate.FileTemplates,
 ) error {
 	if len(templateFiles) == 0 {
-		fmt.Println("no files to create")
+		fc.printf("no files to create\n")
 
 		return nil
 	}
 
-	bar, err := pterm.DefaultProgressbar.WithTotal(len(templateFiles)).WithTitle("Generating files").Start()
+	onConflict, err := parseConflictPolicy(fc.cfg.OnConflict)
 	if err != nil {
 		return err
 	}
 
-	templateValues, err := fc.getTemplateValues(metadata)
+	contents, err := fc.renderFiles(metadata, templateFiles)
 	if err != nil {
 		return err
 	}
 
-	output := metadata.GetDestinationPath()
-	templatePath := metadata.GetTemplatePath()
+	// a progress bar redraws the line a prompt is asked on, so there is none when every conflict is prompted for.
+	bar, err := startProgressbar(fc.quiet || onConflict == conflictPrompt, len(templateFiles), "Generating files")
+	if err != nil {
+		return err
+	}
 
 	for name, templateFile := range templateFiles {
-		data, err := fc.parseTemplate(templatePath, templateFile, templateValues)
+		if err := fc.createFile(metadata, name, templateFile, contents[name], onConflict); err != nil {
+			_ = bar.stop()
+
+			return err
+		}
+		bar.increment()
+	}
+
+	return bar.stop()
+}
+
+// renderFiles generates every file from its template, and reports every template that fails to render together.
+func (fc *filesCreator) renderFiles(
+	metadata *generate.Metadata,
+	templateFiles generate.FileTemplates,
+) (map[string][]byte, error) {
+	contents := make(map[string][]byte, len(templateFiles))
+	failures := make(templateErrors, 0)
+	timestamp := time.Now()
+
+	partials, err := fc.loadPartials(metadata.GetTemplatePath(), fc.templateOptions(metadata)...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range sortedFileNames(templateFiles) {
+		data, err := fc.renderFile(metadata, partials, name, templateFiles[name], timestamp)
+
+		var failure *templateError
+
+		switch {
+		case errors.As(err, &failure):
+			failures = append(failures, failure)
+		case err != nil:
+			return nil, err
+		default:
+			contents[name] = data
+		}
+	}
+
+	if len(failures) > 0 {
+		return nil, failures
+	}
+
+	return contents, nil
+}
+
+// renderFile generates a file from its template, or copies the template when the file is verbatim.
+func (fc *filesCreator) renderFile(
+	metadata *generate.Metadata,
+	partials *template.Template,
+	name string,
+	templateFile *generate.File,
+	timestamp time.Time,
+) ([]byte, error) {
+	if templateFile.GetSymlink() != "" {
+		return nil, nil
+	}
+
+	verbatim, err := isVerbatim(metadata, templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	templateName, text, err := fc.templateText(metadata.GetTemplatePath(), name, templateFile)
+	if err != nil {
+		return nil, newTemplateError(name, templateName, err)
+	}
+
+	if verbatim {
+		return []byte(text), nil
+	}
+
+	options := fc.templateOptions(metadata)
+
+	var templateValues map[string]interface{}
+
+	// the values file is keyed by template, so a file with inline content has no values in it.
+	if templateFile.GetTemplate() != "" {
+		templateValues, err = fc.getTemplateValues(metadata.GetValuesPath(), templateFile.GetVariables(), options...)
 		if err != nil {
-			return errors.Wrapf(err, "failed to parse template %s", templateFile)
+			return nil, err
+		}
+	}
+
+	input, err := fc.templateData(metadata, name, templateFile, templateValues, timestamp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the paths of file %s", name)
+	}
+
+	data, err := fc.parseTemplate(partials, templateName, text, input)
+	if err != nil {
+		return nil, newTemplateError(name, templateName, err)
+	}
+
+	return data, nil
+}
+
+// isVerbatim reports whether a file is copied from its template byte for byte.
+func isVerbatim(metadata *generate.Metadata, templateFile *generate.File) (bool, error) {
+	if templateFile.IsVerbatim() {
+		return true, nil
+	}
+
+	name := filepath.ToSlash(templateFile.GetTemplate())
+	if name == "" {
+		return false, nil
+	}
+
+	for _, pattern := range metadata.GetCopyPatterns() {
+		subject := name
+		if !strings.Contains(pattern, "/") {
+			subject = path.Base(name)
 		}
 
-		destinationPath := output + string(os.PathSeparator) + name
-		if err := afero.WriteFile(fc.fs, destinationPath, data, 0644); err != nil {
-			_, _ = bar.Stop()
+		matched, err := path.Match(pattern, subject)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid copy pattern %q", pattern)
+		}
 
-			return errors.Wrapf(err, "failed to create file %s", destinationPath)
+		if matched {
+			return true, nil
 		}
-		bar.Increment()
 	}
 
-	_, err = bar.Stop()
+	return false, nil
+}
+
+// createFile writes a file generated from its template, unless it conflicts with a file the policy keeps.
+func (fc *filesCreator) createFile(
+	metadata *generate.Metadata,
+	name string,
+	templateFile *generate.File,
+	data []byte,
+	onConflict conflictPolicy,
+) error {
+	destinationPath := metadata.GetDestinationPath() + string(os.PathSeparator) + name
+
+	policy, err := policyFor(templateFile, onConflict)
+	if err != nil {
+		return errors.Wrapf(err, "invalid overwrite setting on file %s", destinationPath)
+	}
+
+	if fc.overwrite {
+		policy = conflictOverwrite
+	}
+
+	if target := templateFile.GetSymlink(); target != "" {
+		return fc.createSymlink(destinationPath, target, policy)
+	}
+
+	mode, explicit, err := fc.fileMode(metadata, name, templateFile)
 	if err != nil {
 		return err
 	}
 
+	write, err := fc.resolveConflict(destinationPath, data, policy)
+	if err != nil || !write {
+		return err
+	}
+
+	if err := afero.WriteFile(fc.fs, destinationPath, data, mode); err != nil {
+		return errors.Wrapf(err, "failed to create file %s", destinationPath)
+	}
+
+	// the mode of a file that already exists is kept by WriteFile, so it is set again.
+	if explicit {
+		if err := fc.fs.Chmod(filepath.Clean(destinationPath), mode); err != nil {
+			return errors.Wrapf(err, "failed to set the mode of file %s", destinationPath)
+		}
+	}
+
+	return writeRecord(fc.fs, metadata.GetDestinationPath(), name, data)
+}
+
+// fileMode returns the permissions a file is created with, and whether they are set for the file.
+func (fc *filesCreator) fileMode(
+	metadata *generate.Metadata,
+	name string,
+	templateFile *generate.File,
+) (os.FileMode, bool, error) {
+	if templateFile.GetMode() != "" {
+		mode, err := parseMode(templateFile.GetMode())
+		if err != nil {
+			return 0, false, errors.Wrapf(err, "invalid mode on file %s", name)
+		}
+
+		return mode, true, nil
+	}
+
+	verbatim, err := isVerbatim(metadata, templateFile)
+	if err != nil || !verbatim || templateFile.GetTemplate() == "" {
+		return 0644, false, err
+	}
+
+	templatePath := metadata.GetTemplatePath() + string(os.PathSeparator) + templateFile.GetTemplate()
+
+	info, err := fc.fs.Stat(templatePath)
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "failed to read the mode of template %s", templatePath)
+	}
+
+	return info.Mode().Perm(), true, nil
+}
+
+// templateOptions returns the options to execute file templates and the values file with.
+func (fc *filesCreator) templateOptions(metadata *generate.Metadata) []string {
+	if fc.cfg.Strict || metadata.IsStrict() {
+		return []string{"missingkey=error"}
+	}
+
 	return nil
 }
 
-func (fc *filesCreator) parseTemplate(
-	templatePath,
-	templateName string,
+// loadPartials returns a template that holds the partials in the partials directory of templatePath.
+func (fc *filesCreator) loadPartials(templatePath string, options ...string) (*template.Template, error) {
+	partials := template.New(partialsDirectory).Funcs(templateFuncs()).Option(options...)
+	directory := templatePath + string(os.PathSeparator) + partialsDirectory
+
+	exists, err := afero.DirExists(fc.fs, directory)
+	if err != nil || !exists {
+		return partials, err
+	}
+
+	entries, err := afero.ReadDir(fc.fs, directory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read partials directory %s", directory)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		contents, err := afero.ReadFile(fc.fs, directory+string(os.PathSeparator)+entry.Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read partial %s", entry.Name())
+		}
+
+		name, _, _ := strings.Cut(entry.Name(), ".")
+		if _, err := partials.New(name).Parse(string(contents)); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse partial %s", entry.Name())
+		}
+	}
+
+	return partials, nil
+}
+
+// templateData returns the variables, values and templateContext the template of a file is executed with.
+func (fc *filesCreator) templateData(
+	metadata *generate.Metadata,
+	name string,
+	templateFile *generate.File,
 	values map[string]interface{},
+	timestamp time.Time,
+) (any, error) {
+	raw := values[templateFile.GetTemplate()]
+	templateValues, ok := raw.(map[string]interface{})
+
+	output, err := filepath.Abs(metadata.GetDestinationPath())
+	if err != nil {
+		return nil, err
+	}
+
+	configFile := metadata.GetConfigFilePath()
+	if configFile != "" {
+		if configFile, err = filepath.Abs(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	path := filepath.Join(output, name)
+	data := mergeValues(mergeValues(templateFile.GetVariables(), templateValues), templateFile.GetValues())
+
+	if !ok && raw != nil {
+		data[valuesKey] = raw
+	}
+
+	data[fundiKey] = &templateContext{
+		Output:     output,
+		Path:       path,
+		Dir:        filepath.Base(filepath.Dir(path)),
+		ConfigFile: configFile,
+		Timestamp:  timestamp,
+	}
+
+	return data, nil
+}
+
+// mergeValues returns a copy of base with the keys of override set on top of it, merging the maps both hold.
+func mergeValues(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range override {
+		baseMap, baseIsMap := merged[key].(map[string]interface{})
+		overrideMap, overrideIsMap := value.(map[string]interface{})
+
+		if baseIsMap && overrideIsMap {
+			merged[key] = mergeValues(baseMap, overrideMap)
+			continue
+		}
+
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// templateText returns the name and the text of the template a file is generated from.
+func (fc *filesCreator) templateText(
+	templatePath,
+	name string,
+	templateFile *generate.File,
+) (string, string, error) {
+	if templateFile.GetContent() != "" {
+		return name, templateFile.GetContent(), nil
+	}
+
+	templateName := templateFile.GetTemplate()
+	if templateName == "" {
+		return "", "", nil
+	}
+
+	path := templatePath + string(os.PathSeparator) + templateName
+
+	contents, err := afero.ReadFile(fc.fs, path)
+	if os.IsNotExist(err) {
+		return templateName, "", errors.Errorf("template %s does not exist", path)
+	}
+
+	return templateName, string(contents), err
+}
+
+// parseTemplate generates a file from the template named templateName, which can pull in the partials.
+func (fc *filesCreator) parseTemplate(
+	partials *template.Template,
+	templateName,
+	text string,
+	data any,
 ) ([]byte, error) {
 	buffer := new(bytes.Buffer)
-	if templateName == "" {
+	if text == "" {
 		return buffer.Bytes(), nil
 	}
 
-	contents, err := afero.ReadFile(fc.fs, templatePath+string(os.PathSeparator)+templateName)
+	library, err := partials.Clone()
 	if err != nil {
-		return buffer.Bytes(), err
+		return nil, err
 	}
 
-	tmpl, err := template.New(templateName).Parse(string(contents))
+	tmpl, err := library.New(templateName).Parse(text)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := tmpl.Execute(buffer, values[templateName]); err != nil {
+	if err := tmpl.Execute(buffer, data); err != nil {
 		return nil, err
 	}
 
 	return buffer.Bytes(), nil
 }
 
-func (fc *filesCreator) getTemplateValues(metadata *generate.Metadata) (map[string]interface{}, error) {
+// getTemplateValues returns the values file, preprocessed with the variables in scope for a file.
+func (fc *filesCreator) getTemplateValues(
+	path string,
+	variables map[string]any,
+	options ...string,
+) (map[string]interface{}, error) {
 	values := make(map[string]interface{})
-	path := metadata.GetValuesPath()
-	variables := metadata.GetVariables()
+	if path == "" {
+		return values, nil
+	}
 
-	data, err := fc.preProcessMetaVariables(path, variables)
+	data, err := fc.preProcessMetaVariables(path, variables, options...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to preprocess placeholders in values file %s", path)
 	}
@@ -236,13 +729,17 @@ func (fc *filesCreator) getTemplateValues(metadata *generate.Metadata) (map[stri
 }
 
 // preProcessMetaVariables replaces template variables in the values file with actual values.
-func (fc *filesCreator) preProcessMetaVariables(path string, variables map[string]any) ([]byte, error) {
+func (fc *filesCreator) preProcessMetaVariables(
+	path string,
+	variables map[string]any,
+	options ...string,
+) ([]byte, error) {
 	valuesFile, err := afero.ReadFile(fc.fs, path)
 	if err != nil {
 		return nil, err
 	}
 
-	tmpl, err := template.New("values-file").Parse(string(valuesFile))
+	tmpl, err := template.New("values-file").Funcs(templateFuncs()).Option(options...).Parse(string(valuesFile))
 	if err != nil {
 		return nil, err
 	}
@@ -253,3 +750,45 @@ func (fc *filesCreator) preProcessMetaVariables(path string, variables map[strin
 	}
 	return buffer.Bytes(), nil
 }
+
+func (creator *directoryCreator) printf(format string, args ...any) {
+	if !creator.quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+func (fc *filesCreator) printf(format string, args ...any) {
+	if !fc.quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+// startProgressbar starts a progress bar with the given total, or a bar that prints nothing when quiet.
+func startProgressbar(quiet bool, total int, title string) (*progressbar, error) {
+	if quiet {
+		return &progressbar{}, nil
+	}
+
+	printer, err := pterm.DefaultProgressbar.WithTotal(total).WithTitle(title).Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return &progressbar{printer: printer}, nil
+}
+
+func (bar *progressbar) increment() {
+	if bar.printer != nil {
+		bar.printer.Increment()
+	}
+}
+
+func (bar *progressbar) stop() error {
+	if bar.printer == nil {
+		return nil
+	}
+
+	_, err := bar.printer.Stop()
+
+	return err
+}
@@ -0,0 +1,454 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+)
+
+type (
+	// workspace is a copy-on-write view of a file system, which keeps writes in a layer over its base.
+	workspace struct {
+		afero.Fs
+		base      afero.Fs
+		layer     afero.Fs
+		links     map[string]string
+		conflicts map[string]conflictPolicy
+		staging   string
+	}
+
+	// change is a directory, file or symbolic link that the workspace holds on top of its base file system.
+	change struct {
+		path   string
+		action string
+		isDir  bool
+		link   string
+	}
+
+	changes []*change
+
+	// undo reverts a step of a commit.
+	undo func() error
+)
+
+// String returns the path of the change, along with the target of a symbolic link.
+func (c *change) String() string {
+	switch {
+	case c.isDir:
+		return c.path + string(os.PathSeparator)
+	case c.link != "":
+		return c.path + " -> " + c.link
+	}
+
+	return c.path
+}
+
+const (
+	actionCreate    = "create"
+	actionOverwrite = "overwrite"
+	actionUnchanged = "unchanged"
+	actionSkip      = "skip"
+	actionMerge     = "merge"
+	actionConflict  = "conflict"
+)
+
+// changes returns what the workspace changes under root, leaving out the state directory.
+func (ws *workspace) changes(root string) (changes, error) {
+	list := make(changes, 0)
+	root = filepath.Clean(root)
+
+	exists, err := afero.Exists(ws.layer, root)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return ws.linkChanges(root, list)
+	}
+
+	err = afero.Walk(ws.layer, root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && path == filepath.Join(root, stateDirectory) {
+			return filepath.SkipDir
+		}
+
+		action, err := ws.compare(path, info)
+		if err != nil {
+			return errors.Wrapf(err, "failed to compare %s", path)
+		}
+
+		if action != "" {
+			list = append(list, &change{path: path, action: action, isDir: info.IsDir()})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.linkChanges(root, list)
+}
+
+// linkChanges appends the changes of the symbolic links under root to list.
+func (ws *workspace) linkChanges(root string, list changes) (changes, error) {
+	for _, path := range ws.linksUnder(root) {
+		current, err := readSymlink(ws.base, path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compare %s", path)
+		}
+
+		action := actionOverwrite
+
+		switch {
+		case ws.conflicts[path] != "":
+			action = ws.conflictAction(path)
+		case current == ws.links[path]:
+			action = actionUnchanged
+		case current == "":
+			action = actionCreate
+		}
+
+		list = append(list, &change{path: path, action: action, link: ws.links[path]})
+	}
+
+	return list, nil
+}
+
+// linksUnder returns the paths of the symbolic links under root, sorted.
+func (ws *workspace) linksUnder(root string) []string {
+	paths := make([]string, 0, len(ws.links))
+	for path := range ws.links {
+		if isWithin(root, path) {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// strays returns the files and symbolic links written to the workspace outside root.
+func (ws *workspace) strays(root string) ([]string, error) {
+	top := string(os.PathSeparator)
+	root = filepath.Join(top, root)
+	strays := make([]string, 0)
+
+	err := afero.Walk(ws.layer, top, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && !isWithin(root, path) {
+			strays = append(strays, path)
+		}
+
+		return nil
+	})
+
+	for path := range ws.links {
+		if !isWithin(root, filepath.Join(top, path)) {
+			strays = append(strays, path)
+		}
+	}
+
+	sort.Strings(strays)
+
+	return strays, err
+}
+
+// isWithin reports whether path is root or is under it.
+func isWithin(root, path string) bool {
+	relative, err := filepath.Rel(root, path)
+
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(os.PathSeparator))
+}
+
+// symlink keeps a symbolic link at path to target, for commit to create in the base file system.
+func (ws *workspace) symlink(target, path string) error {
+	ws.links[filepath.Clean(path)] = target
+
+	return nil
+}
+
+// readlink returns the target of the symbolic link at path, or an empty string when path is not one.
+func (ws *workspace) readlink(path string) (string, error) {
+	if target, ok := ws.links[filepath.Clean(path)]; ok {
+		return target, nil
+	}
+
+	return readSymlink(ws.base, path)
+}
+
+// compare returns what writing path does to the base file system, if anything.
+func (ws *workspace) compare(path string, info os.FileInfo) (string, error) {
+	if info.IsDir() {
+		exists, err := afero.DirExists(ws.base, path)
+		if err != nil || exists {
+			return "", err
+		}
+
+		return actionCreate, nil
+	}
+
+	exists, err := afero.Exists(ws.base, path)
+	if err != nil {
+		return "", err
+	}
+
+	if !exists {
+		return actionCreate, nil
+	}
+
+	current, err := afero.ReadFile(ws.base, path)
+	if err != nil {
+		return "", err
+	}
+
+	generated, err := afero.ReadFile(ws.layer, path)
+	if err != nil {
+		return "", err
+	}
+
+	if bytes.Equal(current, generated) {
+		return actionUnchanged, nil
+	}
+
+	if ws.conflicts[path] != "" {
+		return ws.conflictAction(path), nil
+	}
+
+	return actionOverwrite, nil
+}
+
+// conflict keeps path as a conflict that policy decides, for a dry run to report.
+func (ws *workspace) conflict(path string, policy conflictPolicy) {
+	ws.conflicts[filepath.Clean(path)] = policy
+}
+
+// conflictAction returns the action of a conflict at path, along with its policy.
+func (ws *workspace) conflictAction(path string) string {
+	return fmt.Sprintf("%s (%s)", actionConflict, ws.conflicts[path])
+}
+
+// diff returns a unified diff from the base file system to the workspace for a change.
+func (ws *workspace) diff(c *change) (string, error) {
+	if c.action == actionUnchanged {
+		return "", nil
+	}
+
+	if c.isDir {
+		return fmt.Sprintf("Only in generated project: %s\n", c), nil
+	}
+
+	if c.link != "" {
+		return fmt.Sprintf("Symbolic link in generated project: %s\n", c), nil
+	}
+
+	current := make([]byte, 0)
+	fromFile := "a/" + c.path
+
+	if c.action == actionCreate {
+		fromFile = os.DevNull
+	} else {
+		contents, err := afero.ReadFile(ws.base, c.path)
+		if err != nil {
+			return "", err
+		}
+
+		current = contents
+	}
+
+	generated, err := afero.ReadFile(ws.layer, c.path)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(current),
+		B:        splitLines(generated),
+		FromFile: fromFile,
+		ToFile:   "b/" + c.path,
+		Context:  3,
+	})
+}
+
+// splitLines splits data into lines that each end with a newline, the way a unified diff expects them.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n"
+	}
+
+	return lines
+}
+
+// commit copies what was written under root into the base file system, or undoes the copy when it fails.
+func (ws *workspace) commit(root string) error {
+	strays, err := ws.strays(root)
+	if err != nil {
+		return errors.Wrap(err, "failed to commit the project")
+	}
+
+	if len(strays) > 0 {
+		return errors.Errorf("failed to commit the project: %s is outside of %s", strays[0], root)
+	}
+
+	journal := make([]undo, 0)
+
+	err = afero.Walk(ws.layer, root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		steps, err := ws.commitPath(path, info)
+		journal = append(journal, steps...)
+
+		return err
+	})
+
+	for _, path := range ws.linksUnder(root) {
+		if err != nil {
+			break
+		}
+
+		var steps []undo
+
+		steps, err = ws.commitLink(path, ws.links[path])
+		journal = append(journal, steps...)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	for i := len(journal) - 1; i >= 0; i-- {
+		if undoErr := journal[i](); undoErr != nil {
+			return errors.Wrapf(err, "failed to commit the project, and to undo the commit: %s", undoErr)
+		}
+	}
+
+	return errors.Wrap(err, "failed to commit the project")
+}
+
+// commitPath copies a directory or file from the layer into the base file system, and returns how to undo that.
+func (ws *workspace) commitPath(path string, info os.FileInfo) ([]undo, error) {
+	if info.IsDir() {
+		return ws.commitDir(path)
+	}
+
+	data, err := afero.ReadFile(ws.layer, path)
+	if err != nil {
+		return nil, err
+	}
+
+	restore := func() error { return ws.base.Remove(path) }
+
+	current, err := afero.ReadFile(ws.base, path)
+	switch {
+	case err == nil:
+		restore = func() error { return afero.WriteFile(ws.base, path, current, info.Mode().Perm()) }
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	if err := afero.WriteFile(ws.base, path, data, info.Mode().Perm()); err != nil {
+		return []undo{restore}, errors.Wrapf(err, "failed to write file %s", path)
+	}
+
+	if err := ws.base.Chmod(path, info.Mode().Perm()); err != nil {
+		return []undo{restore}, errors.Wrapf(err, "failed to set the mode of file %s", path)
+	}
+
+	return []undo{restore}, nil
+}
+
+// commitLink creates a symbolic link in the base file system, and returns how to undo that.
+func (ws *workspace) commitLink(path, target string) ([]undo, error) {
+	previous, err := readSymlink(ws.base, path)
+	if err != nil || previous == target {
+		return nil, err
+	}
+
+	restore := func() error {
+		if err := ws.base.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if previous == "" {
+			return nil
+		}
+
+		return writeSymlink(ws.base, path, previous)
+	}
+
+	return []undo{restore}, writeSymlink(ws.base, path, target)
+}
+
+// commitDir creates a directory and its parents in the base file system, and returns how to undo that.
+func (ws *workspace) commitDir(path string) ([]undo, error) {
+	missing := make([]string, 0)
+
+	for dir := path; dir != "." && dir != string(os.PathSeparator); dir = filepath.Dir(dir) {
+		exists, err := afero.DirExists(ws.base, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			break
+		}
+
+		missing = append(missing, dir)
+	}
+
+	steps := make([]undo, 0, len(missing))
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		dir := missing[i]
+
+		mode := os.FileMode(0755)
+		if info, err := ws.layer.Stat(dir); err == nil {
+			mode = info.Mode().Perm()
+		}
+
+		if err := ws.base.Mkdir(dir, mode); err != nil {
+			return steps, errors.Wrapf(err, "failed to create directory %s", dir)
+		}
+
+		steps = append(steps, func() error { return ws.base.Remove(dir) })
+
+		if err := ws.base.Chmod(dir, mode); err != nil {
+			return steps, errors.Wrapf(err, "failed to set the mode of directory %s", dir)
+		}
+	}
+
+	return steps, nil
+}
+
+// discard removes the temporary directory of a staging workspace.
+func (ws *workspace) discard() error {
+	if ws.staging == "" {
+		return nil
+	}
+
+	return ws.base.RemoveAll(ws.staging)
+}
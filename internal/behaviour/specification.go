@@ -26,13 +26,19 @@ type (
 		log        *zap.Logger
 		failures   []string
 		cmd        *cmd
+		cacheDir   string
 		ConfigFile string
 	}
 )
 
 const (
-	testDir  = "./funditest"
-	testData = "./testdata"
+	testDir   = "./funditest"
+	testData  = "./testdata"
+	testState = "./.fundi"
+	testLock  = "./.fundi.lock"
+
+	// cacheDirEnv points fundi at the cache directory of the tests, rather than the cache of the user.
+	cacheDirEnv = "FUNDI_CACHE_DIR"
 )
 
 // NewTestSpecifications provides a new instance of Test.
@@ -95,6 +101,17 @@ func (test *Test) MustCreateDependencies() {
 	if err := afero.NewOsFs().MkdirAll(testData, os.ModePerm); err != nil {
 		test.log.Fatal("failed to create test directory hierarchy", zap.Error(err))
 	}
+
+	test.log.Info("create test cache directory")
+	cacheDir, err := os.MkdirTemp("", "fundi-cache-")
+	if err != nil {
+		test.log.Fatal("failed to create test cache directory", zap.Error(err))
+	}
+
+	test.cacheDir = cacheDir
+	if err := os.Setenv(cacheDirEnv, cacheDir); err != nil {
+		test.log.Fatal("failed to set the test cache directory", zap.Error(err))
+	}
 }
 
 // MustStop frees up all test resources.
@@ -106,6 +123,15 @@ func (test *Test) MustStop() {
 	if err := afero.NewOsFs().RemoveAll(testData); err != nil {
 		test.log.Error("failed to remove test data directory", zap.Error(err))
 	}
+	if err := afero.NewOsFs().RemoveAll(testState); err != nil {
+		test.log.Error("failed to remove test state directory", zap.Error(err))
+	}
+	if err := afero.NewOsFs().RemoveAll(testLock); err != nil {
+		test.log.Error("failed to remove test lock file", zap.Error(err))
+	}
+	if err := afero.NewOsFs().RemoveAll(test.cacheDir); err != nil {
+		test.log.Error("failed to remove test cache directory", zap.Error(err))
+	}
 }
 
 // MustClearState resets the state of the test.
@@ -114,6 +140,12 @@ func (test *Test) MustClearState(scenario *godog.Scenario) {
 	if err := afero.NewOsFs().RemoveAll(testDir); err != nil {
 		test.log.Fatal("failed to remove test directory hierarchy", zap.Error(err))
 	}
+	if err := afero.NewOsFs().RemoveAll(testState); err != nil {
+		test.log.Fatal("failed to remove test state directory", zap.Error(err))
+	}
+	if err := afero.NewOsFs().RemoveAll(testLock); err != nil {
+		test.log.Fatal("failed to remove test lock file", zap.Error(err))
+	}
 }
 
 func (test *Test) commandOutput() string {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"text/template"
 
@@ -84,12 +85,13 @@ func (test *Test) iHaveTheFollowingConfiguration(config *godog.DocString) error
 
 func (test *Test) aFileWithTheFollowingContents(fileName string, fileData *godog.DocString) error {
 	data := []byte(fileData.Content)
+	path := fmt.Sprintf("%s/testdata/%s", test.workingDirectory(), fileName)
 
-	if err := os.WriteFile(
-		fmt.Sprintf("%s/testdata/%s", test.workingDirectory(), fileName),
-		data,
-		0600,
-	); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
 		return err
 	}
 
@@ -5,10 +5,13 @@ import "github.com/spf13/cast"
 // NewMetadata returns an instance of Metadata.
 func NewMetadata(metadata map[string]any) *Metadata {
 	return &Metadata{
-		output:    cast.ToString(metadata[MetaDataOutputKey]),
-		templates: cast.ToString(metadata[MetaDataTemplatesKey]),
-		values:    cast.ToString(metadata[MetaDataValuesKey]),
-		variables: cast.ToStringMap(metadata[MetaDataVariablesKey]),
+		output:     cast.ToString(metadata[MetaDataOutputKey]),
+		templates:  cast.ToString(metadata[MetaDataTemplatesKey]),
+		values:     cast.ToString(metadata[MetaDataValuesKey]),
+		variables:  cast.ToStringMap(metadata[MetaDataVariablesKey]),
+		strict:     cast.ToBool(metadata[MetaDataStrictKey]),
+		configFile: cast.ToString(metadata[MetaDataConfigFileKey]),
+		copy:       cast.ToStringSlice(metadata[MetaDataCopyKey]),
 	}
 }
 
@@ -5,7 +5,7 @@ import "context"
 type (
 	// DirectoryStructureCreator defines CreateDirectoryStructure.
 	DirectoryStructureCreator interface {
-		CreateDirectoryStructure(ctx context.Context, output string, directories []string) error
+		CreateDirectoryStructure(ctx context.Context, output string, directories []string, modes DirectoryModes) error
 	}
 
 	// FilesCreator interface defines CreateFiles.
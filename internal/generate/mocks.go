@@ -11,7 +11,12 @@ import (
 )
 
 type (
-	mockDirectoryStructureCreator     func(ctx context.Context, output string, directories []string) error
+	mockDirectoryStructureCreator func(
+		ctx context.Context,
+		output string,
+		directories []string,
+		modes DirectoryModes,
+	) error
 	inMemoryDirectoryStructureCreator struct {
 		test       *testing.T
 		fileSystem afero.Fs
@@ -28,8 +33,9 @@ func (m mockDirectoryStructureCreator) CreateDirectoryStructure(
 	ctx context.Context,
 	output string,
 	directories []string,
+	modes DirectoryModes,
 ) error {
-	return m(ctx, output, directories)
+	return m(ctx, output, directories, modes)
 }
 
 // CreateDirectoryStructure is implemented by an in memory file system.
@@ -37,6 +43,7 @@ func (m *inMemoryDirectoryStructureCreator) CreateDirectoryStructure(
 	_ context.Context,
 	output string,
 	directories []string,
+	_ DirectoryModes,
 ) error {
 	m.test.Helper()
 
@@ -67,12 +74,12 @@ func (m *inMemoryDirectoryStructureCreator) assertDirectoryStructureExists(dirs
 func (mf *inMemoryFilesCreator) CreateFiles(ctx context.Context, metadata *Metadata, files FileTemplates) error {
 	mf.test.Helper()
 
-	for name, template := range files {
+	for name, file := range files {
 		data := []byte(``)
 		mf.test.Logf("creating file: %s...", name)
 
-		if template != "" {
-			data = []byte(template)
+		if file.template != "" {
+			data = []byte(file.template)
 		}
 
 		if err := afero.WriteFile(mf.fileSystem, name, data, 0644); err != nil {
@@ -1,20 +1,43 @@
 package generate
 
-import "os"
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
 
 type (
 	// Metadata about the project.
 	Metadata struct {
-		output    string
-		templates string
-		values    string
-		variables map[string]any
+		output     string
+		templates  string
+		values     string
+		variables  map[string]any
+		strict     bool
+		configFile string
+		copy       []string
 	}
 
 	// File in the project.
 	File struct {
-		name     string
-		template string
+		name      string
+		template  string
+		skip      bool
+		condition string
+		forEach   string
+		overwrite string
+		content   string
+		verbatim  bool
+		mode      string
+		symlink   string
+		values    map[string]any
+		variables map[string]any
 	}
 
 	// Files is a collection of File.
@@ -25,6 +48,10 @@ type (
 		name           string
 		files          Files
 		subDirectories Directories
+		skip           bool
+		condition      string
+		forEach        string
+		mode           string
 	}
 
 	// Directories is a collection of Directory.
@@ -36,8 +63,19 @@ type (
 		directories Directories
 	}
 
-	// FileTemplates is a map of file and its template.
-	FileTemplates map[string]string
+	// FileTemplates is a map of file path and the file that is generated from a template.
+	FileTemplates map[string]*File
+
+	// Project is what a ConfigurationFile resolves to.
+	Project struct {
+		directories []string
+		modes       DirectoryModes
+		files       FileTemplates
+		skipped     []string
+	}
+
+	// DirectoryModes is a map of directory path and the permissions the directory is created with.
+	DirectoryModes map[string]string
 )
 
 const (
@@ -45,6 +83,13 @@ const (
 	MetaDataTemplatesKey = "templates"
 	MetaDataValuesKey    = "values"
 	MetaDataVariablesKey = "variables"
+	MetaDataStrictKey    = "strict"
+	MetaDataCopyKey      = "copy"
+
+	// MetaDataConfigFileKey is the path of the configuration file the metadata was read from.
+	MetaDataConfigFileKey = "configFile"
+
+	forEachItemKey = "item"
 )
 
 // GetDestinationPath returns destination path where the project will be created.
@@ -67,26 +112,468 @@ func (m *Metadata) GetVariables() map[string]any {
 	return m.variables
 }
 
-func (d *Directory) hasSubDirectories() bool {
-	return d.subDirectories != nil
+// GetConfigFilePath returns the path of the configuration file.
+func (m *Metadata) GetConfigFilePath() string {
+	return m.configFile
+}
+
+// GetCopyPatterns returns the glob patterns of the templates that are copied as they are, instead of rendered.
+func (m *Metadata) GetCopyPatterns() []string {
+	return m.copy
+}
+
+// IsStrict reports whether templates must not refer to missing values.
+func (m *Metadata) IsStrict() bool {
+	return m.strict
+}
+
+// WithSkip marks the file to be left out of the project.
+func (f *File) WithSkip(skip bool) *File {
+	f.skip = skip
+
+	return f
+}
+
+// WithCondition sets a template expression that must hold for the file to be part of the project.
+func (f *File) WithCondition(condition string) *File {
+	f.condition = condition
+
+	return f
+}
+
+// WithForEach repeats the file once for each element of the named list variable.
+func (f *File) WithForEach(variable string) *File {
+	f.forEach = variable
+
+	return f
 }
 
-func (cf *ConfigurationFile) getFilesAndTemplates() FileTemplates {
+// WithOverwrite sets what happens when the file already exists, overriding the policy of the run.
+func (f *File) WithOverwrite(policy string) *File {
+	f.overwrite = policy
+
+	return f
+}
+
+// WithValues sets values of the file's own, which override the values of its template.
+func (f *File) WithValues(values map[string]any) *File {
+	f.values = values
+
+	return f
+}
+
+// GetValues returns the values of the file's own.
+func (f *File) GetValues() map[string]any {
+	return f.values
+}
+
+// WithContent sets an inline template the file is generated from, in place of a template file.
+func (f *File) WithContent(content string) *File {
+	f.content = content
+
+	return f
+}
+
+// GetContent returns the inline template the file is generated from, if any.
+func (f *File) GetContent() string {
+	return f.content
+}
+
+// WithVerbatim marks the file to be copied from its template byte for byte, instead of rendered.
+func (f *File) WithVerbatim(verbatim bool) *File {
+	f.verbatim = verbatim
+
+	return f
+}
+
+// IsVerbatim reports whether the file is copied from its template byte for byte.
+func (f *File) IsVerbatim() bool {
+	return f.verbatim
+}
+
+// WithMode sets the permissions the file is created with, in octal.
+func (f *File) WithMode(mode string) *File {
+	f.mode = mode
+
+	return f
+}
+
+// GetMode returns the permissions the file is created with, or an empty string for the default.
+func (f *File) GetMode() string {
+	return f.mode
+}
+
+// WithSymlink makes the file a symbolic link to target, instead of a file generated from a template.
+func (f *File) WithSymlink(target string) *File {
+	f.symlink = target
+
+	return f
+}
+
+// GetSymlink returns the target of the file when it is a symbolic link, or an empty string.
+func (f *File) GetSymlink() string {
+	return f.symlink
+}
+
+// GetOverwrite returns what happens when the file already exists, or an empty string to follow the policy of the run.
+func (f *File) GetOverwrite() string {
+	return f.overwrite
+}
+
+// GetTemplate returns the name of the template the file is generated from.
+func (f *File) GetTemplate() string {
+	return f.template
+}
+
+// GetVariables returns the variables in scope for the file, including the current element of a foreach.
+func (f *File) GetVariables() map[string]any {
+	return f.variables
+}
+
+// WithSkip marks the directory, and everything under it, to be left out of the project.
+func (d *Directory) WithSkip(skip bool) *Directory {
+	d.skip = skip
+
+	return d
+}
+
+// WithCondition sets a template expression that must hold for the directory to be part of the project.
+func (d *Directory) WithCondition(condition string) *Directory {
+	d.condition = condition
+
+	return d
+}
+
+// WithForEach repeats the directory, and everything under it, once for each element of the named list variable.
+func (d *Directory) WithForEach(variable string) *Directory {
+	d.forEach = variable
+
+	return d
+}
+
+// WithMode sets the permissions the directory is created with, in octal.
+func (d *Directory) WithMode(mode string) *Directory {
+	d.mode = mode
+
+	return d
+}
+
+// GetDirectories returns the paths of the directories in the project.
+func (p *Project) GetDirectories() []string {
+	return p.directories
+}
+
+// GetDirectoryModes returns the permissions of the directories in the project that have them set, by their paths.
+func (p *Project) GetDirectoryModes() DirectoryModes {
+	return p.modes
+}
+
+// GetFiles returns the files in the project by their paths.
+func (p *Project) GetFiles() FileTemplates {
+	return p.files
+}
+
+// GetSkippedPaths returns the paths of the directories and files that were left out of the project.
+func (p *Project) GetSkippedPaths() []string {
+	return p.skipped
+}
+
+// resolve returns a copy of the configuration file that only holds what makes it into the project.
+func (cf *ConfigurationFile) resolve() (*ConfigurationFile, []string, error) {
+	directories, skipped, err := resolveDirectories(cf.directories, "", cf.metadata.variables)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return NewConfigurationFile(cf.metadata, directories), skipped, nil
+}
+
+func resolveDirectories(
+	directories Directories,
+	prefix string,
+	variables map[string]any,
+) (Directories, []string, error) {
+	resolved := make(Directories, 0, len(directories))
+	skipped := make([]string, 0)
+
+	for _, directory := range directories {
+		scopes, err := expand(directory.name, directory.forEach, variables)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to repeat directory %s", joinPath(prefix, directory.name))
+		}
+
+		for _, scope := range scopes {
+			resolvedDirectory, skippedPaths, err := resolveDirectory(directory, prefix, scope)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if resolvedDirectory != nil {
+				resolved = append(resolved, resolvedDirectory)
+			}
+
+			skipped = append(skipped, skippedPaths...)
+		}
+	}
+
+	return resolved, skipped, nil
+}
+
+func resolveDirectory(directory *Directory, prefix string, variables map[string]any) (*Directory, []string, error) {
+	name, err := renderName(directory.name, variables)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to render the name of directory %s", joinPath(prefix, directory.name))
+	}
+
+	path := joinPath(prefix, name)
+
+	included, err := isIncluded(directory.skip, directory.condition, variables)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to evaluate the condition on directory %s", path)
+	}
+
+	if !included {
+		return nil, []string{path}, nil
+	}
+
+	files, skippedFiles, err := resolveFiles(directory.files, path, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subDirectories, skippedSubDirectories, err := resolveDirectories(directory.subDirectories, path, variables)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolved := NewDirectory(name, files, subDirectories).WithMode(directory.mode)
+
+	return resolved, append(skippedFiles, skippedSubDirectories...), nil
+}
+
+func resolveFiles(files Files, prefix string, variables map[string]any) (Files, []string, error) {
+	resolved := make(Files, 0, len(files))
+	skipped := make([]string, 0)
+
+	for _, file := range files {
+		scopes, err := expand(file.name, file.forEach, variables)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to repeat file %s", joinPath(prefix, file.name))
+		}
+
+		for _, scope := range scopes {
+			resolvedFile, path, err := resolveFile(file, prefix, scope)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if resolvedFile == nil {
+				skipped = append(skipped, path)
+				continue
+			}
+
+			resolved = append(resolved, resolvedFile)
+		}
+	}
+
+	return resolved, skipped, nil
+}
+
+// resolveFile returns the file as it goes into the project, or nil when the file is left out, along with its path.
+func resolveFile(file *File, prefix string, variables map[string]any) (*File, string, error) {
+	name, err := renderName(file.name, variables)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to render the name of file %s", joinPath(prefix, file.name))
+	}
+
+	path := joinPath(prefix, name)
+
+	if file.template != "" && file.content != "" {
+		return nil, "", errors.Errorf("file %s has both a template and content, set only one of them", path)
+	}
+
+	if file.symlink != "" && (file.template != "" || file.content != "") {
+		return nil, "", errors.Errorf("file %s is a symlink, so it can't have a template or content", path)
+	}
+
+	included, err := isIncluded(file.skip, file.condition, variables)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to evaluate the condition on file %s", path)
+	}
+
+	if !included {
+		return nil, path, nil
+	}
+
+	resolved := *file
+	resolved.name = name
+	resolved.skip, resolved.condition, resolved.forEach = false, "", ""
+	resolved.variables = variables
+
+	return &resolved, path, nil
+}
+
+// expand returns the variables that each copy of a directory or file is resolved with.
+func expand(name, forEach string, variables map[string]any) ([]map[string]any, error) {
+	if forEach == "" {
+		return []map[string]any{variables}, nil
+	}
+
+	items, err := cast.ToSliceE(variables[forEach])
+	if err != nil {
+		return nil, errors.Errorf("variable %q is not a list", forEach)
+	}
+
+	if !strings.Contains(name, "."+forEachItemKey) {
+		return nil, errors.Errorf("name %q does not refer to .%s, so every copy gets the same name", name,
+			forEachItemKey)
+	}
+
+	scopes := make([]map[string]any, len(items))
+	for i, item := range items {
+		scope := make(map[string]any, len(variables)+1)
+		for key, value := range variables {
+			scope[key] = value
+		}
+
+		scope[forEachItemKey] = item
+		scopes[i] = scope
+	}
+
+	return scopes, nil
+}
+
+// isIncluded reports whether a directory or file makes it into the project.
+func isIncluded(skip bool, condition string, variables map[string]any) (bool, error) {
+	if skip {
+		return false, nil
+	}
+
+	if strings.TrimSpace(condition) == "" {
+		return true, nil
+	}
+
+	result, err := render("when", condition, variables)
+	if err != nil {
+		return false, err
+	}
+
+	switch result = strings.TrimSpace(result); result {
+	case "", "<no value>":
+		return false, nil
+	}
+
+	if included, err := strconv.ParseBool(result); err == nil {
+		return included, nil
+	}
+
+	return true, nil
+}
+
+// renderName executes the name of a directory or file as a template against the variables.
+func renderName(name string, variables map[string]any) (string, error) {
+	rendered, err := render("name", name, variables, "missingkey=error")
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(rendered) == "" {
+		return "", errors.Errorf("name %q renders to an empty string", name)
+	}
+
+	if escapesParent(rendered) {
+		return "", errors.Errorf("name %q renders to %q, which is outside of its parent directory", name, rendered)
+	}
+
+	return rendered, nil
+}
+
+// escapesParent reports whether the path name is absolute or climbs out of the directory it is in.
+func escapesParent(name string) bool {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return true
+	}
+
+	for _, element := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == os.PathSeparator }) {
+		if element == ".." {
+			return true
+		}
+	}
+
+	return false
+}
+
+// render executes text as a template against the variables.
+func render(name, text string, variables map[string]any, options ...string) (string, error) {
+	tmpl, err := template.New(name).Option(options...).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := tmpl.Execute(buffer, variables); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + string(os.PathSeparator) + name
+}
+
+func (cf *ConfigurationFile) getDirectoryModes() DirectoryModes {
+	modes := make(DirectoryModes)
+
+	for _, directory := range cf.directories {
+		addDirectoryMode(directory, modes, directory.name)
+	}
+
+	return modes
+}
+
+func addDirectoryMode(directory *Directory, modes DirectoryModes, path string) {
+	if directory.mode != "" {
+		modes[path] = directory.mode
+	}
+
+	for _, subDirectory := range directory.subDirectories {
+		addDirectoryMode(subDirectory, modes, path+string(os.PathSeparator)+subDirectory.name)
+	}
+}
+
+func (cf *ConfigurationFile) getFilesAndTemplates() (FileTemplates, error) {
 	fileTemplates := make(FileTemplates)
 
 	for _, directory := range cf.directories {
-		addFileAndTemplate(directory, fileTemplates, directory.name)
+		if err := addFileAndTemplate(directory, fileTemplates, directory.name); err != nil {
+			return nil, err
+		}
 	}
 
-	return fileTemplates
+	return fileTemplates, nil
 }
 
-func addFileAndTemplate(directory *Directory, fileTemplates FileTemplates, prefix string) {
+func addFileAndTemplate(directory *Directory, fileTemplates FileTemplates, prefix string) error {
 	for _, file := range directory.files {
-		fileTemplates[prefix+string(os.PathSeparator)+file.name] = file.template
+		path := prefix + string(os.PathSeparator) + file.name
+		if _, ok := fileTemplates[path]; ok {
+			return errors.Errorf("file %s is generated more than once", path)
+		}
+
+		fileTemplates[path] = file
 	}
 
 	for _, subDirectory := range directory.subDirectories {
-		addFileAndTemplate(subDirectory, fileTemplates, prefix+string(os.PathSeparator)+subDirectory.name)
+		err := addFileAndTemplate(subDirectory, fileTemplates, prefix+string(os.PathSeparator)+subDirectory.name)
+		if err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
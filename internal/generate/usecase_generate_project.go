@@ -19,13 +19,12 @@ func (useCase *ProjectUseCase) getAllDirectoriesInTheConfigFile(directories Dire
 	dirs := make([]string, 0)
 
 	for _, directory := range directories {
-		if !directory.hasSubDirectories() {
+		subDirectories := useCase.getAllDirectoriesInTheConfigFile(directory.subDirectories)
+		if len(subDirectories) == 0 {
 			dirs = append(dirs, directory.name)
 			continue
 		}
 
-		subDirectories := useCase.getAllDirectoriesInTheConfigFile(directory.subDirectories)
-
 		for _, dir := range subDirectories {
 			dirs = append(dirs, directory.name+string(os.PathSeparator)+dir)
 		}
@@ -34,13 +33,31 @@ func (useCase *ProjectUseCase) getAllDirectoriesInTheConfigFile(directories Dire
 	return dirs
 }
 
-func (useCase *ProjectUseCase) generateProjectStructure(ctx context.Context, configFile *ConfigurationFile) error {
-	err := useCase.structureCreator.CreateDirectoryStructure(
-		ctx,
-		configFile.metadata.output,
-		useCase.getAllDirectoriesInTheConfigFile(configFile.directories),
-	)
+func (useCase *ProjectUseCase) planProject(configFile *ConfigurationFile) (*Project, error) {
+	resolved, skipped, err := configFile.resolve()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve project structure")
+	}
 
+	files, err := resolved.getFilesAndTemplates()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve project structure")
+	}
+
+	return &Project{
+		directories: useCase.getAllDirectoriesInTheConfigFile(resolved.directories),
+		modes:       resolved.getDirectoryModes(),
+		files:       files,
+		skipped:     skipped,
+	}, nil
+}
+
+func (useCase *ProjectUseCase) generateProjectStructure(
+	ctx context.Context,
+	metadata *Metadata,
+	project *Project,
+) error {
+	err := useCase.structureCreator.CreateDirectoryStructure(ctx, metadata.output, project.directories, project.modes)
 	if err != nil {
 		return errors.Wrap(err, "failed to create project directory structure")
 	}
@@ -48,12 +65,12 @@ func (useCase *ProjectUseCase) generateProjectStructure(ctx context.Context, con
 	return nil
 }
 
-func (useCase *ProjectUseCase) generateFilesFromTemplates(ctx context.Context, configFile *ConfigurationFile) error {
-	if err := useCase.filesCreator.CreateFiles(
-		ctx,
-		configFile.metadata,
-		configFile.getFilesAndTemplates(),
-	); err != nil {
+func (useCase *ProjectUseCase) generateFilesFromTemplates(
+	ctx context.Context,
+	metadata *Metadata,
+	project *Project,
+) error {
+	if err := useCase.filesCreator.CreateFiles(ctx, metadata, project.files); err != nil {
 		return errors.Wrap(err, "failed to create project files")
 	}
 
@@ -61,13 +78,17 @@ func (useCase *ProjectUseCase) generateFilesFromTemplates(ctx context.Context, c
 }
 
 // ScaffoldProject using the provided ConfigurationFile.
-func (useCase *ProjectUseCase) ScaffoldProject(ctx context.Context, configFile *ConfigurationFile) error {
-	if err := useCase.generateProjectStructure(ctx, configFile); err != nil {
-		return err
+func (useCase *ProjectUseCase) ScaffoldProject(ctx context.Context, configFile *ConfigurationFile) (*Project, error) {
+	project, err := useCase.planProject(configFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := useCase.generateProjectStructure(ctx, configFile.metadata, project); err != nil {
+		return nil, err
 	}
-	if err := useCase.generateFilesFromTemplates(ctx, configFile); err != nil {
-		return err
+	if err := useCase.generateFilesFromTemplates(ctx, configFile.metadata, project); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return project, nil
 }
@@ -2,6 +2,7 @@ package generate
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -23,7 +24,7 @@ func TestScaffoldProject(t *testing.T) {
 		"when the directory structure creator fails, return an error": {
 			expectedErr: errors.New("failed to create project directory structure: an-OS-error"),
 			structureCreator: mockDirectoryStructureCreator(
-				func(ctx context.Context, output string, directories []string) error {
+				func(ctx context.Context, output string, directories []string, modes DirectoryModes) error {
 					return errors.New("an-OS-error")
 				},
 			),
@@ -32,7 +33,7 @@ func TestScaffoldProject(t *testing.T) {
 		"when the file creator fails, return an error": {
 			expectedErr: errors.New("failed to create project files: an-OS-error"),
 			structureCreator: mockDirectoryStructureCreator(
-				func(ctx context.Context, output string, directories []string) error {
+				func(ctx context.Context, output string, directories []string, modes DirectoryModes) error {
 					return nil
 				},
 			),
@@ -64,7 +65,7 @@ func TestScaffoldProject(t *testing.T) {
 			t.Parallel()
 
 			useCase := NewProjectUseCase(testCase.structureCreator, testCase.fileCreator)
-			err := useCase.ScaffoldProject(context.Background(), testCase.configFile)
+			_, err := useCase.ScaffoldProject(context.Background(), testCase.configFile)
 
 			switch testCase.expectedErr != nil {
 			case true:
@@ -102,7 +103,7 @@ func TestGetAllDirectoriesInTheConfigFile(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 			useCase := NewProjectUseCase(mockDirectoryStructureCreator(
-				func(ctx context.Context, output string, directories []string) error {
+				func(ctx context.Context, output string, directories []string, modes DirectoryModes) error {
 					return nil
 				},
 			), nil)
@@ -119,11 +120,11 @@ func TestGetFilesAndTemplates(t *testing.T) {
 		configFile            *ConfigurationFile
 	}{
 		"returns all files templates in the configurationFile": {
-			expectedFileTemplates: FileTemplates{
+			expectedFileTemplates: newTestFileTemplates(nil, map[string]string{
 				"project_root_directory/README.md":                 "README.md.tmpl",
 				"project_root_directory/cmd/main.go":               "main.go.tmpl",
 				"project_root_directory/internal/domain/domain.go": "domain.go.tmpl",
-			},
+			}),
 			configFile: NewTestConfigurationFile(),
 		},
 		"returns an empty list of file templates": {
@@ -137,7 +138,356 @@ func TestGetFilesAndTemplates(t *testing.T) {
 
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
-			assert.Equal(t, testCase.expectedFileTemplates, testCase.configFile.getFilesAndTemplates())
+			fileTemplates, err := testCase.configFile.getFilesAndTemplates()
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedFileTemplates, fileTemplates)
+		})
+	}
+}
+
+func TestGetDirectoryModes(t *testing.T) {
+	tests := map[string]struct {
+		expectedModes DirectoryModes
+		configFile    *ConfigurationFile
+	}{
+		"returns the modes of the directories that have one, by their paths": {
+			expectedModes: DirectoryModes{
+				"project_root_directory/cmd":      "0750",
+				"project_root_directory/internal": "0700",
+			},
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.subDirectories[0].mode = "0750"
+				root.subDirectories[1].mode = "0700"
+			}),
+		},
+		"returns no modes when no directory has one": {
+			expectedModes: DirectoryModes{},
+			configFile:    NewTestConfigurationFile(),
+		},
+	}
+
+	for name, testCase := range tests {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.expectedModes, testCase.configFile.getDirectoryModes())
+		})
+	}
+}
+
+func TestPlanProject(t *testing.T) {
+	tests := map[string]struct {
+		expectedErr     error
+		expectedDirs    []string
+		expectedFiles   FileTemplates
+		expectedSkipped []string
+		configFile      *ConfigurationFile
+	}{
+		"when nothing is left out, plan every directory and file": {
+			expectedDirs: []string{
+				"project_root_directory/cmd",
+				"project_root_directory/internal/domain",
+			},
+			expectedFiles: newTestFileTemplates(newTestVariables(), map[string]string{
+				"project_root_directory/README.md":                 "README.md.tmpl",
+				"project_root_directory/cmd/main.go":               "main.go.tmpl",
+				"project_root_directory/internal/domain/domain.go": "domain.go.tmpl",
+			}),
+			expectedSkipped: make([]string, 0),
+			configFile:      newTestConfigurationFile(func(*Directory) {}),
+		},
+		"when directories and files are skipped, leave them and everything under them out": {
+			expectedDirs: []string{
+				"project_root_directory/cmd",
+				"project_root_directory/internal",
+			},
+			expectedFiles: newTestFileTemplates(newTestVariables(), map[string]string{
+				"project_root_directory/README.md": "README.md.tmpl",
+			}),
+			expectedSkipped: []string{
+				"project_root_directory/cmd/main.go",
+				"project_root_directory/internal/domain",
+			},
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.subDirectories[0].files[0].skip = true
+				root.subDirectories[1].subDirectories[0].skip = true
+			}),
+		},
+		"when conditions do not hold, leave the directories and files out": {
+			expectedDirs: []string{
+				"project_root_directory/cmd",
+				"project_root_directory/internal",
+			},
+			expectedFiles: newTestFileTemplates(newTestVariables(), map[string]string{
+				"project_root_directory/cmd/main.go": "main.go.tmpl",
+			}),
+			expectedSkipped: []string{
+				"project_root_directory/README.md",
+				"project_root_directory/internal/domain",
+			},
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.files[0].condition = "{{ .withReadme }}"
+				root.subDirectories[0].condition = "{{ .withCLI }}"
+				root.subDirectories[1].subDirectories[0].condition = `{{ eq .database "postgres" }}`
+			}),
+		},
+		"when names are templates, render them against the variables": {
+			expectedDirs: []string{
+				"fundi/cmd",
+				"fundi/internal/billing",
+			},
+			expectedFiles: newTestFileTemplates(newTestVariables(), map[string]string{
+				"fundi/README.md":                           "README.md.tmpl",
+				"fundi/cmd/main.go":                         "main.go.tmpl",
+				"fundi/internal/billing/billing_handler.go": "domain.go.tmpl",
+			}),
+			expectedSkipped: make([]string, 0),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.name = "{{ .project }}"
+				root.subDirectories[1].subDirectories[0].name = "{{ .service }}"
+				root.subDirectories[1].subDirectories[0].files[0].name = "{{ .service }}_handler.go"
+			}),
+		},
+		"when a name refers to a missing variable, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: failed to render the name of file " +
+					"project_root_directory/{{ .module }}.go: template: name:1:3: executing \"name\" at <.module>: " +
+					"map has no entry for key \"module\"",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.files[0].name = "{{ .module }}.go"
+			}),
+		},
+		"when a name renders to an empty string, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: failed to render the name of directory " +
+					"project_root_directory/{{ if .withCLI }}{{ end }}: name \"{{ if .withCLI }}{{ end }}\" renders to an empty string",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.subDirectories[0].name = "{{ if .withCLI }}{{ end }}"
+			}),
+		},
+		"when a name is a path, plan the directories along it": {
+			expectedDirs: []string{
+				"project_root_directory/.github/workflows",
+				"project_root_directory/internal/domain",
+			},
+			expectedFiles: newTestFileTemplates(newTestVariables(), map[string]string{
+				"project_root_directory/README.md":                 "README.md.tmpl",
+				"project_root_directory/.github/workflows/main.go": "main.go.tmpl",
+				"project_root_directory/internal/domain/domain.go": "domain.go.tmpl",
+			}),
+			expectedSkipped: make([]string, 0),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.subDirectories[0].name = ".github/workflows"
+			}),
+		},
+		"when a name renders to a path outside of its parent, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: failed to render the name of directory " +
+					"project_root_directory/../escape: name \"../escape\" renders to \"../escape\", which is outside of " +
+					"its parent directory",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.subDirectories[0].name = "../escape"
+			}),
+		},
+		"when a name renders to the parent directory, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: failed to render the name of file project_root_directory/..: " +
+					"name \"..\" renders to \"..\", which is outside of its parent directory",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.files[0].name = ".."
+			}),
+		},
+		"when a name renders to an absolute path, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: failed to render the name of file " +
+					"project_root_directory//etc/{{ .project }}: name \"/etc/{{ .project }}\" renders to \"/etc/fundi\", " +
+					"which is outside of its parent directory",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.files[0].name = "/etc/{{ .project }}"
+			}),
+		},
+		"when directories and files repeat for each element of a list, resolve one copy per element": {
+			expectedDirs: []string{
+				"project_root_directory/cmd",
+				"project_root_directory/internal/billing",
+				"project_root_directory/internal/payments",
+			},
+			expectedFiles: FileTemplates{
+				"project_root_directory/README.md": &File{
+					name: "README.md", template: "README.md.tmpl", variables: newTestVariables(),
+				},
+				"project_root_directory/cmd/main.go": &File{
+					name: "main.go", template: "main.go.tmpl", variables: newTestVariables(),
+				},
+				"project_root_directory/internal/billing/billing.go": &File{
+					name: "billing.go", template: "domain.go.tmpl", variables: newTestScope("billing"),
+				},
+				"project_root_directory/internal/payments/payments.go": &File{
+					name: "payments.go", template: "domain.go.tmpl", variables: newTestScope("payments"),
+				},
+				"project_root_directory/internal/payments/README.md": &File{
+					name: "README.md", template: "README.md.tmpl", variables: newTestScope("payments"),
+				},
+			},
+			expectedSkipped: []string{
+				"project_root_directory/internal/billing/README.md",
+			},
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				domain := root.subDirectories[1].subDirectories[0]
+				domain.name = "{{ .item }}"
+				domain.forEach = "domains"
+				domain.files[0].name = "{{ .item }}.go"
+				domain.files = append(domain.files, &File{
+					name:      "README.md",
+					template:  "README.md.tmpl",
+					condition: `{{ eq .item "payments" }}`,
+				})
+			}),
+		},
+		"when files repeat for each element of a list, resolve one copy per element": {
+			expectedDirs: []string{
+				"project_root_directory/cmd",
+				"project_root_directory/internal/domain",
+			},
+			expectedFiles: FileTemplates{
+				"project_root_directory/README.md": &File{
+					name: "README.md", template: "README.md.tmpl", variables: newTestVariables(),
+				},
+				"project_root_directory/cmd/billing.go": &File{
+					name: "billing.go", template: "main.go.tmpl", variables: newTestScope("billing"),
+				},
+				"project_root_directory/cmd/payments.go": &File{
+					name: "payments.go", template: "main.go.tmpl", variables: newTestScope("payments"),
+				},
+				"project_root_directory/internal/domain/domain.go": &File{
+					name: "domain.go", template: "domain.go.tmpl", variables: newTestVariables(),
+				},
+			},
+			expectedSkipped: make([]string, 0),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.subDirectories[0].files[0].name = "{{ .item }}.go"
+				root.subDirectories[0].files[0].forEach = "domains"
+			}),
+		},
+		"when a foreach names a variable that is not a list, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: failed to repeat directory project_root_directory/cmd: " +
+					"variable \"project\" is not a list",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.subDirectories[0].forEach = "project"
+			}),
+		},
+		"when a foreach directory does not refer to the item in its name, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: failed to repeat directory project_root_directory/internal/" +
+					"domain: name \"domain\" does not refer to .item, so every copy gets the same name",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.subDirectories[1].subDirectories[0].forEach = "domains"
+			}),
+		},
+		"when two files are generated at the same path, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: file project_root_directory/cmd/main.go is generated more " +
+					"than once",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.subDirectories[0].files = append(root.subDirectories[0].files, &File{
+					name:    "main.go",
+					content: "package main",
+				})
+			}),
+		},
+		"when a condition is not a valid template, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: failed to evaluate the condition on directory " +
+					"project_root_directory/cmd: template: when:1: unclosed action",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.subDirectories[0].condition = "{{ .withCLI"
+			}),
+		},
+		"when a file has both a template and content, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: file project_root_directory/README.md has both a template " +
+					"and content, set only one of them",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.files[0].content = "# {{ .project }}"
+			}),
+		},
+		"when a symlink has a template, return an error": {
+			expectedErr: errors.New(
+				"failed to resolve project structure: file project_root_directory/README.md is a symlink, so it " +
+					"can't have a template or content",
+			),
+			configFile: newTestConfigurationFile(func(root *Directory) {
+				root.files[0].symlink = "docs/README.md"
+			}),
+		},
+	}
+
+	for name, testCase := range tests {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			useCase := NewProjectUseCase(nil, nil)
+
+			project, err := useCase.planProject(testCase.configFile)
+
+			switch testCase.expectedErr != nil {
+			case true:
+				assert.EqualError(t, err, testCase.expectedErr.Error())
+			case false:
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expectedDirs, project.directories)
+				assert.Equal(t, testCase.expectedFiles, project.files)
+				assert.Equal(t, testCase.expectedSkipped, project.GetSkippedPaths())
+			}
 		})
 	}
 }
+
+func newTestConfigurationFile(modify func(root *Directory)) *ConfigurationFile {
+	configFile := NewTestConfigurationFile()
+	configFile.metadata.variables = newTestVariables()
+	modify(configFile.directories[0])
+
+	return configFile
+}
+
+func newTestVariables() map[string]any {
+	return map[string]any{
+		"withReadme": false,
+		"withCLI":    true,
+		"database":   "sqlite",
+		"project":    "fundi",
+		"service":    "billing",
+		"domains":    []any{"billing", "payments"},
+	}
+}
+
+func newTestScope(item any) map[string]any {
+	scope := newTestVariables()
+	scope["item"] = item
+
+	return scope
+}
+
+func newTestFileTemplates(variables map[string]any, templates map[string]string) FileTemplates {
+	fileTemplates := make(FileTemplates, len(templates))
+	for path, template := range templates {
+		fileTemplates[path] = &File{name: filepath.Base(path), template: template, variables: variables}
+	}
+
+	return fileTemplates
+}